@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	libSvm "github.com/climber544/libsvm-go/lib"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: svm-demo [options] data_set\n")
+	fmt.Fprintf(os.Stderr, "Trains on data_set.train, writes data_set.model and reports accuracy on data_set.test\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	param := libSvm.NewParameter()
+
+	flag.Usage = usage
+	flag.IntVar(&param.SvmType, "s", param.SvmType, "svm type (0: C-SVC, 1: nu-SVC, 2: one-class, 3: epsilon-SVR, 4: nu-SVR)")
+	flag.IntVar(&param.KernelType, "t", param.KernelType, "kernel type (0: linear, 1: polynomial, 2: rbf, 3: sigmoid)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	var filename string = flag.Arg(0)
+
+	var prob libSvm.Problem
+	if err := prob.Read(libSvm.GetTrainFileName(filename), param); err != nil { // read training file data
+		fmt.Fprintln(os.Stderr, "Fail to read problem: ", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Problem size = %v\n", prob.ProblemSize())
+	model := libSvm.NewModel(param)
+	if err := model.Train(&prob); err != nil {
+		var stopped *libSvm.TrainStoppedError
+		if !errors.As(err, &stopped) || !stopped.Partial {
+			fmt.Fprintln(os.Stderr, "Fail to train model: ", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "Warning: ", err)
+	}
+
+	if err := model.Dump(libSvm.GetModelFileName(filename)); err != nil {
+		fmt.Fprintln(os.Stderr, "Fail to save model: ", err)
+		os.Exit(1)
+	}
+
+	var testProb libSvm.Problem
+	if err := testProb.Read(libSvm.GetTestFileName(filename), param); err != nil { // read test file data
+		fmt.Fprintln(os.Stderr, "Fail to read test problem: ", err)
+		os.Exit(1)
+	}
+	if err := testProb.SetLabelEncoder(model.LabelEncoder()); err != nil { // use the codes of the model
+		fmt.Fprintln(os.Stderr, "Fail to read test problem: ", err)
+		os.Exit(1)
+	}
+
+	var predictFail int = 0
+	for testProb.Begin(); !testProb.Done(); testProb.Next() {
+		actualY, x := testProb.Get()
+		predictY := model.Predict(x)
+		if actualY != predictY {
+			predictFail++
+		}
+	}
+
+	fmt.Printf("Accuracy = %.6v\n", 100-(float64(predictFail)*100)/float64(testProb.ProblemSize()))
+}
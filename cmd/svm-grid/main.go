@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	libSvm "github.com/climber544/libsvm-go/lib"
+)
+
+func exitWithHelp() {
+	fmt.Print(
+		"Usage: svm-grid [grid_options] [svm_options] dataset\n" +
+			"grid_options :\n" +
+			"-log2c {begin,end,step | \"null\"} : set the range of c (default -5,15,2)\n" +
+			"    begin,end,step -- c_range = 2^{begin,...,begin+k*step,...,end}\n" +
+			"    \"null\"         -- do not grid with c\n" +
+			"-log2g {begin,end,step | \"null\"} : set the range of g (default 3,-15,-2)\n" +
+			"    begin,end,step -- g_range = 2^{begin,...,begin+k*step,...,end}\n" +
+			"    \"null\"         -- do not grid with g\n" +
+			"-log2p {begin,end,step | \"null\"} : set the range of p for epsilon-SVR (default -8,-1,1)\n" +
+			"-nu {begin,end,step | \"null\"} : set the range of nu for nu-SVC, one-class SVM and nu-SVR (default null)\n" +
+			"-v n : n-fold cross validation (default 5)\n" +
+			"-S seed : seed of the random cross-validation folds, for reproducible scores (default 0: random)\n" +
+			"-j workers : number of concurrent cross validations (default: number of CPUs)\n" +
+			"-out {pathname | \"null\"} : set output file path and name (default dataset.out)\n" +
+			"-resume [pathname] : resume the grid task using an existing output file (default pathname is dataset.out)\n" +
+			"-csv pathname : write the score of every grid point as CSV, e.g. for a contour plot\n" +
+			"-q : quiet mode (no outputs but the best parameters)\n" +
+			"svm_options : additional options for svm-train: -s, -t, -d, -r, -c, -g, -n, -p, -m, -e, -h, -b, -wi, -B\n" +
+			"    -c, -g, -n and -p are used for the parameters that are not searched\n")
+	os.Exit(1)
+}
+
+type options struct {
+	grid       *libSvm.GridSearch
+	dataFile   string
+	outFile    string
+	resumeFile string
+	csvFile    string
+	quiet      bool
+	weightOf   []string // class label of each -wi option, resolved once the labels are read
+}
+
+/**
+ * Parses "begin,end,step" or "null" into the values of a grid axis
+ */
+func parseRange(value string) ([]float64, error) {
+	if value == "null" {
+		return nil, nil
+	}
+	fields := strings.Split(value, ",")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("want begin,end,step")
+	}
+	var r [3]float64
+	for i, field := range fields {
+		var err error
+		if r[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return nil, err
+		}
+	}
+	return libSvm.GridRange(r[0], r[1], r[2]), nil
+}
+
+func parseCommandLine(args []string) options {
+	param := libSvm.NewParameter()
+	var opt options
+
+	ranges := make(map[string]string) // grid option -> value, applied once the svm type is known
+	var nrFold int = 5
+	var resume bool = false
+	var out string
+
+	var i int
+	for i = 0; i < len(args)-1; i++ { // the last argument is the dataset
+		if len(args[i]) < 2 || args[i][0] != '-' {
+			exitWithHelp()
+		}
+
+		option := args[i][1:]
+		switch option {
+		case "q":
+			opt.quiet = true
+			continue
+		case "resume":
+			resume = true
+			if i+1 < len(args)-1 && len(args[i+1]) > 0 && args[i+1][0] != '-' {
+				i++
+				opt.resumeFile = args[i]
+			}
+			continue
+		}
+
+		i++
+		if i >= len(args)-1 {
+			exitWithHelp()
+		}
+		value := args[i]
+
+		var err error
+		switch option {
+		case "log2c", "log2g", "log2p", "nu":
+			ranges[option] = value
+			_, err = parseRange(value)
+		case "v":
+			nrFold, err = strconv.Atoi(value)
+			if err == nil && nrFold < 2 {
+				fmt.Fprintf(os.Stderr, "n-fold cross validation: n must >= 2\n")
+				exitWithHelp()
+			}
+		case "S":
+			param.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "j":
+			param.Parallelism, err = strconv.Atoi(value)
+		case "out":
+			out = value
+		case "csv":
+			opt.csvFile = value
+		case "s":
+			param.SvmType, err = strconv.Atoi(value)
+		case "t":
+			param.KernelType, err = strconv.Atoi(value)
+		case "d":
+			param.Degree, err = strconv.Atoi(value)
+		case "r":
+			param.Coef0, err = strconv.ParseFloat(value, 64)
+		case "c":
+			param.C, err = strconv.ParseFloat(value, 64)
+		case "g":
+			param.Gamma, err = strconv.ParseFloat(value, 64)
+		case "n":
+			param.Nu, err = strconv.ParseFloat(value, 64)
+		case "p":
+			param.P, err = strconv.ParseFloat(value, 64)
+		case "m":
+			param.CacheSize, err = strconv.ParseFloat(value, 64)
+		case "e":
+			param.Eps, err = strconv.ParseFloat(value, 64)
+		case "h":
+			var h int
+			h, err = strconv.Atoi(value)
+			param.Shrinking = h != 0
+		case "b":
+			var b int
+			b, err = strconv.Atoi(value)
+			param.Probability = b != 0
+		case "B":
+			var b int
+			b, err = strconv.Atoi(value)
+			param.Balanced = b != 0
+		default:
+			if option[0] != 'w' {
+				fmt.Fprintf(os.Stderr, "Unknown option: -%s\n", option)
+				exitWithHelp()
+			}
+			var weight float64
+			weight, err = strconv.ParseFloat(value, 64)
+			opt.weightOf = append(opt.weightOf, option[1:])
+			param.Weight = append(param.Weight, weight)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid value %q for option -%s\n", value, option)
+			exitWithHelp()
+		}
+	}
+	if i != len(args)-1 {
+		exitWithHelp()
+	}
+	opt.dataFile = args[i]
+
+	param.QuietMode = true // the cross validations run concurrently
+	opt.grid = libSvm.NewGridSearch(param)
+	opt.grid.NrFold = nrFold
+	for option, value := range ranges {
+		values, _ := parseRange(value)
+		switch option {
+		case "log2c":
+			opt.grid.Log2C = values
+		case "log2g":
+			opt.grid.Log2Gamma = values
+		case "log2p":
+			opt.grid.Log2P = values
+		case "nu":
+			opt.grid.Nu = values
+		}
+	}
+
+	defaultOut := filepath.Base(opt.dataFile) + ".out"
+	switch out {
+	case "":
+		opt.outFile = defaultOut
+	case "null":
+		opt.outFile = ""
+	default:
+		opt.outFile = out
+	}
+	if resume && opt.resumeFile == "" {
+		opt.resumeFile = defaultOut
+	}
+
+	return opt
+}
+
+/**
+ * Sets the class weights of the -wi options. With string labels, i is a class name and is mapped to
+ * its code in the LabelEncoder of prob.
+ */
+func setClassWeights(prob *libSvm.Problem, param *libSvm.Parameter, weightOf []string) {
+	weights := param.Weight
+	param.NrWeight, param.WeightLabel, param.Weight = 0, nil, nil
+	for k, name := range weightOf {
+		var label int
+		if labels := prob.LabelEncoder(); labels != nil {
+			code, ok := labels.Code(name)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "WARNING: class label %s specified in weight is not found\n", name)
+				continue
+			}
+			label = int(code)
+		} else {
+			var err error
+			if label, err = strconv.Atoi(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid class label %q for option -w%s\n", name, name)
+				exitWithHelp()
+			}
+		}
+		param.NrWeight++
+		param.WeightLabel = append(param.WeightLabel, label)
+		param.Weight = append(param.Weight, weights[k])
+	}
+}
+
+func exitOnError(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	os.Exit(1)
+}
+
+func main() {
+	opt := parseCommandLine(os.Args[1:])
+	grid := opt.grid
+	param := grid.Param
+
+	var prob libSvm.Problem
+	if err := prob.Read(opt.dataFile, param); err != nil {
+		exitOnError("can't read input file %s: %v\n", opt.dataFile, strings.TrimSpace(err.Error()))
+	}
+	setClassWeights(&prob, param, opt.weightOf)
+
+	var previous []byte
+	if opt.resumeFile != "" {
+		var err error
+		if previous, err = os.ReadFile(opt.resumeFile); err != nil {
+			exitOnError("can't read results file %s: %v\n", opt.resumeFile, err)
+		}
+		if grid.Previous, err = libSvm.ReadGridResults(strings.NewReader(string(previous))); err != nil {
+			exitOnError("can't read results file %s: %v\n", opt.resumeFile, strings.TrimSpace(err.Error()))
+		}
+	}
+
+	var results []io.Writer
+	if !opt.quiet {
+		results = append(results, os.Stdout)
+	}
+	if opt.outFile != "" {
+		flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if opt.outFile == opt.resumeFile {
+			flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			previous = nil
+		}
+		f, err := os.OpenFile(opt.outFile, flag, 0644)
+		if err != nil {
+			exitOnError("can't open output file %s: %v\n", opt.outFile, err)
+		}
+		defer f.Close()
+
+		if _, err := f.Write(previous); err != nil { // keep the resumed results in the new file
+			exitOnError("can't write output file %s: %v\n", opt.outFile, err)
+		}
+		results = append(results, f)
+	}
+	grid.Results = io.MultiWriter(results...)
+
+	result, err := grid.Run(&prob)
+	if err != nil {
+		exitOnError("ERROR: %s\n", strings.TrimSpace(err.Error()))
+	}
+
+	if opt.csvFile != "" {
+		f, err := os.Create(opt.csvFile)
+		if err != nil {
+			exitOnError("can't open CSV file %s: %v\n", opt.csvFile, err)
+		}
+		if err := result.WriteCSV(f); err != nil {
+			exitOnError("can't write CSV file %s: %v\n", opt.csvFile, err)
+		}
+		f.Close()
+	}
+
+	// the best parameters and score, like grid.py: the values of the searched axes then the rate
+	best := result.Best
+	var line []string
+	if grid.Log2C != nil {
+		line = append(line, strconv.FormatFloat(math.Exp2(best.Log2C), 'g', -1, 64))
+	}
+	if grid.Log2Gamma != nil {
+		line = append(line, strconv.FormatFloat(math.Exp2(best.Log2Gamma), 'g', -1, 64))
+	}
+	if grid.Nu != nil {
+		line = append(line, strconv.FormatFloat(best.Nu, 'g', -1, 64))
+	}
+	if grid.Log2P != nil {
+		line = append(line, strconv.FormatFloat(math.Exp2(best.Log2P), 'g', -1, 64))
+	}
+	line = append(line, strconv.FormatFloat(best.Score, 'g', -1, 64))
+	fmt.Println(strings.Join(line, " "))
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	libSvm "github.com/climber544/libsvm-go/lib"
+)
+
+func exitWithHelp() {
+	fmt.Print(
+		"Usage: svm-predict [options] test_file model_file output_file\n" +
+			"options:\n" +
+			"-b probability_estimates: whether to predict probability estimates, 0 or 1 (default 0); for one-class SVM only 0 is supported\n" +
+			"-q : quiet mode (no outputs)\n")
+	os.Exit(1)
+}
+
+var quiet bool = false
+
+func info(format string, a ...interface{}) {
+	if !quiet {
+		fmt.Printf(format, a...)
+	}
+}
+
+func exitInputError(msg string) {
+	fmt.Fprintf(os.Stderr, "%s\n", strings.TrimSpace(msg))
+	os.Exit(1)
+}
+
+/**
+ * Returns the original label of y: the string label if labels is not nil, the formatted number otherwise
+ */
+func labelName(labels *libSvm.LabelEncoder, y float64) string {
+	if labels != nil {
+		return labels.Decode(y)
+	}
+	return strconv.FormatFloat(y, 'g', -1, 64)
+}
+
+func predict(testProb *libSvm.Problem, model *libSvm.Model, output *bufio.Writer, predictProbability bool) {
+	var correct int = 0
+	var total int = 0
+	var errorSum, sumP, sumT, sumPP, sumTT, sumPT float64
+
+	svmType := model.SvmType()
+	nrClass := model.NrClass()
+	modelLabels := model.LabelEncoder()   // nil unless the model was trained on string labels
+	testLabels := testProb.LabelEncoder() // nil unless the test file has string labels
+
+	if predictProbability {
+		if svmType == libSvm.NU_SVR || svmType == libSvm.EPSILON_SVR {
+			info("Prob. model for test data: target value = predicted value + z,\n"+
+				"z: Laplace distribution e^(-|z|/sigma)/(2sigma),sigma=%g\n", model.SvrProbability())
+		} else {
+			fmt.Fprint(output, "labels")
+			for _, label := range model.LabelNames() {
+				fmt.Fprintf(output, " %s", label)
+			}
+			fmt.Fprint(output, "\n")
+		}
+	}
+
+	withProbability := predictProbability && (svmType == libSvm.C_SVC || svmType == libSvm.NU_SVC)
+
+	// score all the instances in parallel
+	var predictLabels []float64
+	var probabilityEstimates [][]float64
+	if withProbability {
+		predictLabels, probabilityEstimates = model.PredictProbabilityBatch(testProb)
+	} else {
+		predictLabels, _ = model.PredictBatch(testProb)
+	}
+
+	for testProb.Begin(); !testProb.Done(); testProb.Next() {
+		targetLabel, _ := testProb.Get()
+
+		predictLabel := predictLabels[total]
+		if withProbability {
+			probEstimates := probabilityEstimates[total]
+			if modelLabels != nil {
+				fmt.Fprint(output, modelLabels.Decode(predictLabel))
+			} else {
+				fmt.Fprintf(output, "%g", predictLabel)
+			}
+			for j := 0; j < nrClass; j++ {
+				fmt.Fprintf(output, " %g", probEstimates[j])
+			}
+			fmt.Fprint(output, "\n")
+		} else {
+			if modelLabels != nil {
+				fmt.Fprintf(output, "%s\n", modelLabels.Decode(predictLabel))
+			} else {
+				fmt.Fprintf(output, "%.17g\n", predictLabel)
+			}
+		}
+
+		if modelLabels != nil || testLabels != nil { // compare the original labels
+			if labelName(modelLabels, predictLabel) == labelName(testLabels, targetLabel) {
+				correct++
+			}
+		} else if predictLabel == targetLabel {
+			correct++
+		}
+		errorSum += (predictLabel - targetLabel) * (predictLabel - targetLabel)
+		sumP += predictLabel
+		sumT += targetLabel
+		sumPP += predictLabel * predictLabel
+		sumTT += targetLabel * targetLabel
+		sumPT += predictLabel * targetLabel
+		total++
+	}
+
+	if svmType == libSvm.NU_SVR || svmType == libSvm.EPSILON_SVR {
+		n := float64(total)
+		info("Mean squared error = %g (regression)\n", errorSum/n)
+		info("Squared correlation coefficient = %g (regression)\n",
+			((n*sumPT-sumP*sumT)*(n*sumPT-sumP*sumT))/
+				((n*sumPP-sumP*sumP)*(n*sumTT-sumT*sumT)))
+	} else {
+		info("Accuracy = %g%% (%d/%d) (classification)\n",
+			float64(correct)/float64(total)*100, correct, total)
+	}
+}
+
+func main() {
+	var predictProbability bool = false
+
+	args := os.Args[1:]
+	var i int
+	for i = 0; i < len(args); i++ {
+		if len(args[i]) < 2 || args[i][0] != '-' {
+			break
+		}
+		switch args[i][1] {
+		case 'b':
+			i++
+			if i >= len(args) {
+				exitWithHelp()
+			}
+			b, err := strconv.Atoi(args[i])
+			if err != nil {
+				exitInputError(fmt.Sprintf("Invalid value %q for option -b", args[i]))
+			}
+			predictProbability = b != 0
+		case 'q':
+			quiet = true
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: -%c\n", args[i][1])
+			exitWithHelp()
+		}
+	}
+	if i != len(args)-3 {
+		exitWithHelp()
+	}
+	testFile, modelFile, outputFile := args[i], args[i+1], args[i+2]
+
+	param := libSvm.NewParameter()
+	param.QuietMode = quiet
+	model := libSvm.NewModel(param)
+	if err := model.ReadModel(modelFile); err != nil {
+		exitInputError(fmt.Sprintf("can't open model file %s: %v", modelFile, err))
+	}
+
+	if predictProbability {
+		if !model.CheckProbabilityModel() {
+			exitInputError("Model does not support probabiliy estimates")
+		}
+	} else if model.CheckProbabilityModel() {
+		info("Model supports probability estimates, but disabled in prediction.\n")
+	}
+
+	var testProb libSvm.Problem
+	if err := testProb.Read(testFile, libSvm.NewParameter()); err != nil {
+		exitInputError(fmt.Sprintf("can't open input file %s: %v", testFile, err))
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		exitInputError(fmt.Sprintf("can't open output file %s", outputFile))
+	}
+	defer f.Close()
+
+	output := bufio.NewWriter(f)
+	predict(&testProb, model, output, predictProbability)
+	if err := output.Flush(); err != nil {
+		exitInputError(fmt.Sprintf("can't write output file %s: %v", outputFile, err))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	libSvm "github.com/climber544/libsvm-go/lib"
+)
+
+func exitWithHelp() {
+	fmt.Print(
+		"Usage: svm-scale [options] data_filename\n" +
+			"options:\n" +
+			"-l lower : x scaling lower limit (default -1)\n" +
+			"-u upper : x scaling upper limit (default +1)\n" +
+			"-y y_lower y_upper : y scaling limits (default: no y scaling)\n" +
+			"-s save_filename : save scaling parameters to save_filename\n" +
+			"-r restore_filename : restore scaling parameters from restore_filename\n")
+	os.Exit(1)
+}
+
+func parseFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid value %q\n", s)
+		exitWithHelp()
+	}
+	return v
+}
+
+func main() {
+	var lower, upper float64 = -1, 1
+	var yScaling bool = false
+	var yLower, yUpper float64
+	var saveFile, restoreFile string
+
+	args := os.Args[1:]
+	var i int
+	for i = 0; i < len(args); i++ {
+		if len(args[i]) < 2 || args[i][0] != '-' {
+			break
+		}
+		if i+1 >= len(args) {
+			exitWithHelp()
+		}
+		switch args[i][1] {
+		case 'l':
+			i++
+			lower = parseFloat(args[i])
+		case 'u':
+			i++
+			upper = parseFloat(args[i])
+		case 'y':
+			if i+2 >= len(args) {
+				exitWithHelp()
+			}
+			yLower = parseFloat(args[i+1])
+			yUpper = parseFloat(args[i+2])
+			yScaling = true
+			i += 2
+		case 's':
+			i++
+			saveFile = args[i]
+		case 'r':
+			i++
+			restoreFile = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "unknown option\n")
+			exitWithHelp()
+		}
+	}
+
+	if !(upper > lower) || (yScaling && !(yUpper > yLower)) {
+		fmt.Fprintf(os.Stderr, "inconsistent lower/upper specification\n")
+		os.Exit(1)
+	}
+
+	if restoreFile != "" && saveFile != "" {
+		fmt.Fprintf(os.Stderr, "cannot use -r and -s simultaneously\n")
+		os.Exit(1)
+	}
+
+	if i != len(args)-1 {
+		exitWithHelp()
+	}
+	dataFile := args[i]
+
+	var prob libSvm.Problem
+	if err := prob.Read(dataFile, libSvm.NewParameter()); err != nil {
+		fmt.Fprintf(os.Stderr, "can't open file %s: %v", dataFile, err)
+		os.Exit(1)
+	}
+	if yScaling && prob.LabelEncoder() != nil {
+		fmt.Fprintf(os.Stderr, "cannot scale the string labels of %s with -y\n", dataFile)
+		os.Exit(1)
+	}
+
+	scaler := libSvm.NewScaler(lower, upper)
+	if restoreFile != "" {
+		if err := scaler.RestoreRange(restoreFile); err != nil {
+			fmt.Fprintf(os.Stderr, "can't open file %s: %v", restoreFile, err)
+			os.Exit(1)
+		}
+	} else {
+		if yScaling {
+			scaler.SetYScaling(yLower, yUpper)
+		}
+		scaler.Fit(&prob)
+	}
+
+	if saveFile != "" {
+		if err := scaler.SaveRange(saveFile); err != nil {
+			fmt.Fprintf(os.Stderr, "can't open file %s: %v", saveFile, err)
+			os.Exit(1)
+		}
+	}
+
+	scaled := scaler.Transform(&prob)
+
+	var nonzerosOld, nonzerosNew int
+	for prob.Begin(); !prob.Done(); prob.Next() {
+		_, x := prob.Get()
+		nonzerosOld += len(x)
+	}
+
+	for scaled.Begin(); !scaled.Done(); scaled.Next() {
+		_, x := scaled.Get()
+		nonzerosNew += len(x)
+	}
+
+	// written through the problem so that string labels and instance weights are kept
+	if err := scaled.WriteLIBSVM(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "can't write output: %v\n", err)
+		os.Exit(1)
+	}
+
+	if nonzerosNew > nonzerosOld {
+		fmt.Fprintf(os.Stderr,
+			"WARNING: original #nonzeros %d\n"+
+				"       > new      #nonzeros %d\n"+
+				"If feature values are non-negative and sparse, use -l 0 rather than the default -l -1\n",
+			nonzerosOld, nonzerosNew)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	libSvm "github.com/climber544/libsvm-go/lib"
+)
+
+func exitWithHelp() {
+	fmt.Print(
+		"Usage: svm-train [options] training_set_file [model_file]\n" +
+			"options:\n" +
+			"-s svm_type : set type of SVM (default 0)\n" +
+			"	0 -- C-SVC		(multi-class classification)\n" +
+			"	1 -- nu-SVC		(multi-class classification)\n" +
+			"	2 -- one-class SVM\n" +
+			"	3 -- epsilon-SVR	(regression)\n" +
+			"	4 -- nu-SVR		(regression)\n" +
+			"-t kernel_type : set type of kernel function (default 2)\n" +
+			"	0 -- linear: u'*v\n" +
+			"	1 -- polynomial: (gamma*u'*v + coef0)^degree\n" +
+			"	2 -- radial basis function: exp(-gamma*|u-v|^2)\n" +
+			"	3 -- sigmoid: tanh(gamma*u'*v + coef0)\n" +
+			"	4 -- precomputed kernel (kernel values in training_set_file)\n" +
+			"-d degree : set degree in kernel function (default 3)\n" +
+			"-g gamma : set gamma in kernel function (default 1/num_features)\n" +
+			"-r coef0 : set coef0 in kernel function (default 0)\n" +
+			"-c cost : set the parameter C of C-SVC, epsilon-SVR, and nu-SVR (default 1)\n" +
+			"-n nu : set the parameter nu of nu-SVC, one-class SVM, and nu-SVR (default 0.5)\n" +
+			"-p epsilon : set the epsilon in loss function of epsilon-SVR (default 0.1)\n" +
+			"-m cachesize : set cache memory size in MB (default 100)\n" +
+			"-e epsilon : set tolerance of termination criterion (default 0.001)\n" +
+			"-h shrinking : whether to use the shrinking heuristics, 0 or 1 (default 1)\n" +
+			"-b probability_estimates : whether to train a SVC or SVR model for probability estimates, 0 or 1 (default 0)\n" +
+			"-wi weight : set the parameter C of class i to weight*C, for C-SVC (default 1); i may be a string label\n" +
+			"-B balanced : whether to weight the classes inversely proportional to their frequency, 0 or 1 (default 0)\n" +
+			"-W weight_file : set the weight of each instance, one weight per line (default: weights in training_set_file or 1)\n" +
+			"-S seed : seed of the random folds of cross validation and probability estimates (default 0: random)\n" +
+			"-v n: n-fold cross validation mode\n" +
+			"-q : quiet mode (no outputs)\n")
+	os.Exit(1)
+}
+
+type options struct {
+	param      *libSvm.Parameter
+	nrFold     int
+	trainFile  string
+	modelFile  string
+	weightFile string
+	weightOf   []string // class label of each -wi option, resolved once the labels are read
+}
+
+func parseCommandLine(args []string) options {
+	opt := options{param: libSvm.NewParameter()}
+	param := opt.param
+
+	var i int
+	for i = 0; i < len(args); i++ {
+		if len(args[i]) == 0 || args[i][0] != '-' {
+			break
+		}
+		if len(args[i]) < 2 {
+			exitWithHelp()
+		}
+
+		flag := args[i][1]
+		if flag == 'q' { // the only flag without an argument
+			param.QuietMode = true
+			continue
+		}
+
+		i++
+		if i >= len(args) {
+			exitWithHelp()
+		}
+		value := args[i]
+
+		var err error
+		switch flag {
+		case 's':
+			param.SvmType, err = strconv.Atoi(value)
+		case 't':
+			param.KernelType, err = strconv.Atoi(value)
+		case 'd':
+			param.Degree, err = strconv.Atoi(value)
+		case 'g':
+			param.Gamma, err = strconv.ParseFloat(value, 64)
+		case 'r':
+			param.Coef0, err = strconv.ParseFloat(value, 64)
+		case 'n':
+			param.Nu, err = strconv.ParseFloat(value, 64)
+		case 'm':
+			param.CacheSize, err = strconv.ParseFloat(value, 64)
+		case 'c':
+			param.C, err = strconv.ParseFloat(value, 64)
+		case 'e':
+			param.Eps, err = strconv.ParseFloat(value, 64)
+		case 'p':
+			param.P, err = strconv.ParseFloat(value, 64)
+		case 'h':
+			var h int
+			h, err = strconv.Atoi(value)
+			param.Shrinking = h != 0
+		case 'b':
+			var b int
+			b, err = strconv.Atoi(value)
+			param.Probability = b != 0
+		case 'v':
+			opt.nrFold, err = strconv.Atoi(value)
+			if err == nil && opt.nrFold < 2 {
+				fmt.Fprintf(os.Stderr, "n-fold cross validation: n must >= 2\n")
+				exitWithHelp()
+			}
+		case 'B':
+			var b int
+			b, err = strconv.Atoi(value)
+			param.Balanced = b != 0
+		case 'W':
+			opt.weightFile = value
+		case 'S':
+			param.Seed, err = strconv.ParseInt(value, 10, 64)
+		case 'w':
+			var weight float64
+			weight, err = strconv.ParseFloat(value, 64)
+			opt.weightOf = append(opt.weightOf, args[i-1][2:])
+			param.Weight = append(param.Weight, weight)
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: -%c\n", flag)
+			exitWithHelp()
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid value %q for option %s\n", value, args[i-1])
+			exitWithHelp()
+		}
+	}
+
+	// determine filenames
+	if i >= len(args) {
+		exitWithHelp()
+	}
+	opt.trainFile = args[i]
+
+	if i < len(args)-1 {
+		opt.modelFile = args[i+1]
+	} else {
+		opt.modelFile = libSvm.GetModelFileName(filepath.Base(opt.trainFile))
+	}
+
+	return opt
+}
+
+/**
+ * Sets the class weights of the -wi options. With string labels, i is a class name and is mapped to
+ * its code in the LabelEncoder of prob.
+ */
+func setClassWeights(prob *libSvm.Problem, param *libSvm.Parameter, weightOf []string) {
+	weights := param.Weight
+	param.NrWeight, param.WeightLabel, param.Weight = 0, nil, nil
+	for k, name := range weightOf {
+		var label int
+		if labels := prob.LabelEncoder(); labels != nil {
+			code, ok := labels.Code(name)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "WARNING: class label %s specified in weight is not found\n", name)
+				continue
+			}
+			label = int(code)
+		} else {
+			var err error
+			if label, err = strconv.Atoi(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid class label %q for option -w%s\n", name, name)
+				exitWithHelp()
+			}
+		}
+		param.NrWeight++
+		param.WeightLabel = append(param.WeightLabel, label)
+		param.Weight = append(param.Weight, weights[k])
+	}
+}
+
+func readWeights(prob *libSvm.Problem, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return prob.ReadWeights(f)
+}
+
+func doCrossValidation(prob *libSvm.Problem, param *libSvm.Parameter, nrFold int) {
+	target, err := libSvm.CrossValidationContext(context.Background(), prob, param, nrFold)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", strings.TrimSpace(err.Error()))
+		os.Exit(1)
+	}
+
+	if param.SvmType == libSvm.EPSILON_SVR || param.SvmType == libSvm.NU_SVR {
+		metrics, err := libSvm.EvaluateRegression(prob, target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cross Validation Mean squared error = %g\n", metrics.MSE)
+		fmt.Printf("Cross Validation Squared correlation coefficient = %g\n", metrics.SquaredCorrelation)
+	} else {
+		metrics, err := libSvm.EvaluateClassification(prob, target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cross Validation Accuracy = %g%%\n", 100.0*metrics.Accuracy)
+	}
+}
+
+func main() {
+	opt := parseCommandLine(os.Args[1:])
+	param := opt.param
+
+	var prob libSvm.Problem
+	if err := prob.Read(opt.trainFile, param); err != nil {
+		fmt.Fprintf(os.Stderr, "can't read input file %s: %v\n", opt.trainFile, strings.TrimSpace(err.Error()))
+		os.Exit(1)
+	}
+
+	setClassWeights(&prob, param, opt.weightOf)
+
+	if opt.weightFile != "" {
+		if err := readWeights(&prob, opt.weightFile); err != nil {
+			fmt.Fprintf(os.Stderr, "can't read weight file %s: %v\n", opt.weightFile, strings.TrimSpace(err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	if err := param.Validate(&prob); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+
+	if opt.nrFold > 0 {
+		doCrossValidation(&prob, param, opt.nrFold)
+		return
+	}
+
+	model := libSvm.NewModel(param)
+	if err := model.Train(&prob); err != nil {
+		var stopped *libSvm.TrainStoppedError
+		if !errors.As(err, &stopped) || !stopped.Partial {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", strings.TrimSpace(err.Error()))
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err) // LIBSVM also saves the model of a solver at its iteration limit
+	}
+
+	if err := model.Dump(opt.modelFile); err != nil {
+		fmt.Fprintf(os.Stderr, "can't save model to file %s\n", opt.modelFile)
+		os.Exit(1)
+	}
+}
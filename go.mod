@@ -0,0 +1,3 @@
+module github.com/climber544/libsvm-go
+
+go 1.21
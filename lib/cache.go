@@ -1,44 +1,42 @@
-package main
+package libSvm
 
 import (
 	"container/list"
-	"fmt"
 )
 
 type cacheNode struct {
 	index    int           // column index for which this cacheNode is caching
 	refCount int           // number of times this column was referenced
-	data     []float64     // valid slice from cache::cacheBuffer if in the cache
-	offset   int           // offset in cache::cacheBuffer data field is referring too
+	data     []float64     // column data if in the cache, nil otherwise
+	len      int           // data[0:len] holds valid values
 	element  *list.Element // list element (iterator) if in LRU list
 }
 
 type cache struct {
-	head            []cacheNode // all the possible cached columns
-	colSize         int         // size of each column
-	cacheAvail      int         // number of additional columns we can store
-	cacheBuffer     []float64   // pre-allocated buffer for cache
-	availableOffset int         // next available offset in cacheBuffer
-	hits, misses    int         // cache statistics
-	cacheList       *list.List  // LRU list
+	head         []cacheNode // all the possible cached columns
+	colSize      int         // size of each column
+	cacheAvail   int         // number of additional columns we can store
+	cacheSize    int         // maximum number of columns we can store
+	hits, misses int         // cache statistics
+	cacheList    *list.List  // LRU list
+	param        *Parameter
 }
 
 const sizeOfFloat64 = 8
 
-func (c *cache) getData(i int) ([]float64, bool) {
-	var newData bool = true
-
+/**
+ * Returns the cached column i and the number of leading values of it that are already valid.
+ * The caller has to fill in data[start:length]; afterwards data[0:length] is considered valid.
+ */
+func (c *cache) getData(i, length int) (data []float64, start int) {
 	c.head[i].refCount++ // count reference to this index
 
-	if c.head[i].offset != -1 {
+	if c.head[i].data != nil {
 		h := &(c.head[i])
 		c.cacheList.Remove(h.element) // Remove from LRU list so we can re-insert into the back
-		newData = false
 		c.hits++
-	}
-
-	if newData {
-		var useOffset int = 0
+	} else {
+		var buffer []float64
 		// new data
 		if c.cacheAvail == 0 { // no more space in cache
 			// free a column
@@ -48,58 +46,110 @@ func (c *cache) getData(i int) ([]float64, bool) {
 			value := c.cacheList.Remove(oldElement)
 			old := value.(*cacheNode)
 
-			useOffset = old.offset // reuse its memory
-			old.offset = -1
-
-			c.cacheAvail++
+			buffer = old.data // reuse its memory
+			old.data = nil
+			old.len = 0
+			old.element = nil
 		} else {
-			useOffset = c.availableOffset
-			c.availableOffset += c.colSize
+			buffer = make([]float64, c.colSize) // columns are allocated as they are needed
+			c.cacheAvail--
 		}
 
-		c.head[i].data = c.cacheBuffer[useOffset : useOffset+c.colSize]
-		c.head[i].offset = useOffset // remember which offsef this cache line has been assigned
-
-		c.cacheAvail--
+		c.head[i].data = buffer
+		c.head[i].len = 0
 
 		c.misses++
 	}
 
 	h := &(c.head[i])
 	h.element = c.cacheList.PushBack(h) // Inserts it at the back of LRU circular list
-	return c.head[i].data, newData
+
+	start = h.len
+	if length > h.len {
+		h.len = length
+	}
+	return h.data, start
+}
+
+/**
+ * Swaps the columns i and j, and the rows i and j of every cached column.
+ * Used by the solver when shrinking the active set.
+ */
+func (c *cache) swapIndex(i, j int) {
+	if i == j {
+		return
+	}
+
+	hi := &(c.head[i])
+	hj := &(c.head[j])
+	hi.data, hj.data = hj.data, hi.data
+	hi.len, hj.len = hj.len, hi.len
+	hi.element, hj.element = hj.element, hi.element
+	if hi.element != nil {
+		hi.element.Value = hi
+	}
+	if hj.element != nil {
+		hj.element.Value = hj
+	}
+
+	if i > j {
+		i, j = j, i
+	}
+	for e := c.cacheList.Front(); e != nil; e = e.Next() {
+		h := e.Value.(*cacheNode)
+		if h.len > i {
+			if h.len > j {
+				h.data[i], h.data[j] = h.data[j], h.data[i]
+			} else {
+				h.len = i // row j is not valid in this column, so keep only the rows before i
+			}
+		}
+	}
 }
 
-func (c cache) stats() {
-	fmt.Printf("Cache misses:     %d\n", c.misses)
-	fmt.Printf("Cache hits:       %d\n", c.hits)
-	fmt.Printf("Cache efficiency: %.6f%%\n", float32(c.hits)/float32(c.hits+c.misses)*100)
+/**
+ * Returns the number of bytes currently allocated for cached columns
+ */
+func (c *cache) usedBytes() int {
+	return (c.cacheSize - c.cacheAvail) * c.colSize * sizeOfFloat64
 }
 
-func computeCacheSize(colSize int) int {
-	var cacheSize int = 500 // MB (default)
+func (c *cache) stats() {
+	var efficiency float64 = 0
+	if c.hits+c.misses > 0 {
+		efficiency = float64(c.hits) / float64(c.hits+c.misses) * 100
+	}
+	c.param.info("Cache: %d hits, %d misses (%.2f%% efficiency), %.1f MB used of %g MB\n",
+		c.hits, c.misses, efficiency, float64(c.usedBytes())/(1<<20), c.param.CacheSize)
+}
 
-	cacheSizeBytes := cacheSize * (1 << 20)
-	numCols := cacheSizeBytes / (colSize * sizeOfFloat64) // num of columns we can store
-	numCols = maxi(2, numCols)                            // we should be able to store at least 2
+/**
+ * Returns the number of columns of colSize values that fit in cacheSize MB. There is no need for
+ * more than l columns, and we should be able to store at least 2.
+ */
+func computeCacheSize(l, colSize int, cacheSize float64) int {
+	cacheSizeBytes := int64(cacheSize * (1 << 20))
+	numCols := cacheSizeBytes / int64(maxi(colSize, 1)*sizeOfFloat64) // num of columns we can store
+	if numCols > int64(l) {
+		numCols = int64(l)
+	}
 
-	return numCols
+	return maxi(2, int(numCols))
 }
 
-func NewCache(l, colSize int) *cache {
+func NewCache(l, colSize int, param *Parameter) *cache {
 
-	colCacheSize := computeCacheSize(colSize) // number of columns we can cache
+	colCacheSize := computeCacheSize(l, colSize, param.CacheSize) // number of columns we can cache
 
 	head := make([]cacheNode, l)
 	for i := 0; i < l; i++ {
 		head[i].index = i
 		head[i].refCount = 0
 		head[i].data = nil
-		head[i].offset = -1
+		head[i].len = 0
 	}
 
-	c := cache{head: head, colSize: colSize, cacheAvail: colCacheSize, availableOffset: 0, hits: 0, misses: 0}
-	c.cacheBuffer = make([]float64, colCacheSize*colSize)
+	c := cache{head: head, colSize: colSize, cacheAvail: colCacheSize, cacheSize: colCacheSize, hits: 0, misses: 0, param: param}
 	c.cacheList = list.New()
 
 	return &c
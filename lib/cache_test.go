@@ -0,0 +1,52 @@
+package libSvm
+
+import (
+	"testing"
+)
+
+func TestCacheSize(t *testing.T) {
+	if n := computeCacheSize(1000, 1000, 1); n != 131 {
+		t.Errorf("1 MB holds %d columns of 1000 values, want 131", n)
+	}
+	if n := computeCacheSize(10, 10, 100); n != 10 {
+		t.Errorf("cache for l=10 holds %d columns, want 10", n)
+	}
+	if n := computeCacheSize(1000, 1000000, 1); n != 2 {
+		t.Errorf("cache holds %d columns, want at least 2", n)
+	}
+}
+
+func TestCacheGetDataAndSwap(t *testing.T) {
+	param := NewParameter()
+	param.CacheSize = 3 * 4 * sizeOfFloat64 / float64(1<<20) // room for 3 columns
+	c := NewCache(4, 4, param)
+
+	fill := func(i, length int) {
+		data, start := c.getData(i, length)
+		for j := start; j < length; j++ {
+			data[j] = float64(10*i + j)
+		}
+	}
+
+	fill(0, 2)
+	if _, start := c.getData(0, 4); start != 2 {
+		t.Errorf("column 0 has %d valid rows, want 2", start)
+	}
+	fill(0, 4)
+	fill(1, 4)
+	fill(2, 4)
+	fill(3, 4) // evicts column 0, the least recently used
+	if c.head[0].data != nil {
+		t.Errorf("column 0 should have been evicted")
+	}
+
+	c.swapIndex(1, 3)
+	data, start := c.getData(1, 4)
+	if start != 4 || data[1] != 33 || data[3] != 31 || data[0] != 30 {
+		t.Errorf("after swap column 1 = %v (%d valid), want [30 33 32 31]", data, start)
+	}
+
+	if used := c.usedBytes(); used != 3*4*sizeOfFloat64 {
+		t.Errorf("cache uses %d bytes, want %d", used, 3*4*sizeOfFloat64)
+	}
+}
@@ -0,0 +1,285 @@
+package libSvm
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+/**
+ * Predictor is a compiled form of a Model for fast prediction. It caches the squared norms of the
+ * support vectors for the RBF kernel and reuses scratch buffers between calls.
+ *
+ * A Predictor is safe for concurrent use by multiple goroutines. The model it was built from must
+ * not be modified (e.g. retrained or read again) while the Predictor is in use.
+ */
+type Predictor struct {
+	model   *Model
+	svNorm  []float64 // dot(sv, sv) of each support vector, for the RBF kernel
+	start   []int     // first support vector of each class, for classification
+	scratch sync.Pool // *predictScratch, one in use per goroutine at a time
+}
+
+type predictScratch struct {
+	keys   []int     // sorted feature indices of the instance
+	px     []snode   // the instance in snode form
+	kvalue []float64 // kernel value of the instance with each support vector
+	vote   []int
+}
+
+/**
+ * Returns a Predictor for the trained or restored model.
+ */
+func NewPredictor(model *Model) *Predictor {
+	p := &Predictor{model: model}
+
+	if model.param.KernelType == RBF {
+		p.svNorm = make([]float64, model.l)
+		for i := 0; i < model.l; i++ {
+			py := model.svSpace[model.sV[i]:]
+			p.svNorm[i] = dot(py, py)
+		}
+	}
+
+	if model.param.SvmType == C_SVC || model.param.SvmType == NU_SVC {
+		p.start = make([]int, model.nrClass)
+		for i := 1; i < model.nrClass; i++ {
+			p.start[i] = p.start[i-1] + model.nSV[i-1]
+		}
+	}
+
+	p.scratch.New = func() interface{} {
+		return &predictScratch{kvalue: make([]float64, model.l), vote: make([]int, model.nrClass)}
+	}
+
+	return p
+}
+
+/**
+ * Same as Model.Predict.
+ */
+func (p *Predictor) Predict(x map[int]float64) float64 {
+	s := p.scratch.Get().(*predictScratch)
+	defer p.scratch.Put(s)
+
+	return p.predictValues(s, x, nil)
+}
+
+/**
+ * Same as Model.PredictValues.
+ */
+func (p *Predictor) PredictValues(x map[int]float64) (float64, []float64) {
+	s := p.scratch.Get().(*predictScratch)
+	defer p.scratch.Put(s)
+
+	decisionValues := make([]float64, p.nrDecisionValues())
+	return p.predictValues(s, x, decisionValues), decisionValues
+}
+
+/**
+ * Same as Model.PredictProbability.
+ */
+func (p *Predictor) PredictProbability(x map[int]float64) (float64, []float64) {
+	model := p.model
+	if !((model.param.SvmType == C_SVC || model.param.SvmType == NU_SVC) && model.probA != nil && model.probB != nil) {
+		return p.Predict(x), nil
+	}
+
+	_, decisionValues := p.PredictValues(x)
+	return model.probabilityFromDecisionValues(decisionValues)
+}
+
+/**
+ * Same as Model.PredictLabel.
+ */
+func (p *Predictor) PredictLabel(x map[int]float64) string {
+	return p.model.labelName(p.Predict(x))
+}
+
+func (p *Predictor) nrDecisionValues() int {
+	if p.start != nil {
+		return p.model.nrClass * (p.model.nrClass - 1) / 2
+	}
+	return 1
+}
+
+/**
+ * Converts x into the scratch snode buffer, in ascending index order
+ */
+func (s *predictScratch) setInstance(x map[int]float64) []snode {
+	s.keys = s.keys[:0]
+	for k := range x {
+		s.keys = append(s.keys, k)
+	}
+	sort.Ints(s.keys)
+
+	s.px = s.px[:0]
+	for _, k := range s.keys {
+		s.px = append(s.px, snode{index: k, value: x[k]})
+	}
+	s.px = append(s.px, snode{index: -1})
+	return s.px
+}
+
+func (p *Predictor) kernelValue(px []snode, pxNorm float64, i int) float64 {
+	model := p.model
+	py := model.svSpace[model.sV[i]:]
+	if p.svNorm != nil { // RBF
+		q := pxNorm + p.svNorm[i] - 2*dot(px, py)
+		return math.Exp(-model.param.Gamma * q)
+	}
+	return computeKernelValue(px, py, model.param)
+}
+
+/**
+ * Computes the prediction for x like Model.PredictValues; the decision values are stored in
+ * decisionValues unless it is nil
+ */
+func (p *Predictor) predictValues(s *predictScratch, x map[int]float64, decisionValues []float64) float64 {
+	return p.predictSnode(s, s.setInstance(x), decisionValues)
+}
+
+func (p *Predictor) predictSnode(s *predictScratch, px []snode, decisionValues []float64) float64 {
+	model := p.model
+
+	var pxNorm float64 = 0
+	if p.svNorm != nil {
+		pxNorm = dot(px, px)
+	}
+
+	switch model.param.SvmType {
+	case ONE_CLASS, EPSILON_SVR, NU_SVR:
+		svCoef := model.svCoef[0]
+
+		var sum float64 = 0
+		for i := 0; i < model.l; i++ {
+			sum += svCoef[i] * p.kernelValue(px, pxNorm, i)
+		}
+		sum -= model.rho[0]
+
+		if decisionValues != nil {
+			decisionValues[0] = sum
+		}
+
+		if model.param.SvmType == ONE_CLASS {
+			if sum > 0 {
+				return 1
+			}
+			return -1
+		}
+		return sum
+
+	case C_SVC, NU_SVC:
+		var nrClass int = model.nrClass
+
+		for i := 0; i < model.l; i++ {
+			s.kvalue[i] = p.kernelValue(px, pxNorm, i)
+		}
+
+		for i := 0; i < nrClass; i++ {
+			s.vote[i] = 0
+		}
+
+		var k int = 0
+		for i := 0; i < nrClass; i++ {
+			for j := i + 1; j < nrClass; j++ {
+				var sum float64 = 0
+
+				si, sj := p.start[i], p.start[j]
+				ci, cj := model.nSV[i], model.nSV[j]
+
+				coef1 := model.svCoef[j-1]
+				coef2 := model.svCoef[i]
+				for m := 0; m < ci; m++ {
+					sum += coef1[si+m] * s.kvalue[si+m]
+				}
+				for m := 0; m < cj; m++ {
+					sum += coef2[sj+m] * s.kvalue[sj+m]
+				}
+				sum -= model.rho[k]
+
+				if decisionValues != nil {
+					decisionValues[k] = sum
+				}
+				if sum > 0 {
+					s.vote[i]++
+				} else {
+					s.vote[j]++
+				}
+				k++
+			}
+		}
+
+		var maxIdx int = 0
+		for i := 1; i < nrClass; i++ {
+			if s.vote[i] > s.vote[maxIdx] {
+				maxIdx = i
+			}
+		}
+		return model.label[maxIdx]
+	}
+
+	return 0
+}
+
+/**
+ * Predicts every instance of prob in parallel, using at most Parameter.Parallelism goroutines.
+ * Returns the predicted labels (or function values) and the decision values of each instance,
+ * as Model.PredictValues does.
+ */
+func (model *Model) PredictBatch(prob *Problem) (labels []float64, decisionValues [][]float64) {
+	p := NewPredictor(model)
+
+	labels = make([]float64, prob.l)
+	decisionValues = make([][]float64, prob.l)
+	p.runBatch(prob, func(s *predictScratch, i int, px []snode) {
+		decisionValues[i] = make([]float64, p.nrDecisionValues())
+		labels[i] = p.predictSnode(s, px, decisionValues[i])
+	})
+
+	return labels, decisionValues
+}
+
+/**
+ * Same as PredictBatch, but returns the probability estimates of each instance, as
+ * Model.PredictProbability does. The estimates are nil if the model has no probability information.
+ */
+func (model *Model) PredictProbabilityBatch(prob *Problem) (labels []float64, probabilityEstimates [][]float64) {
+	p := NewPredictor(model)
+	withProbability := (model.param.SvmType == C_SVC || model.param.SvmType == NU_SVC) &&
+		model.probA != nil && model.probB != nil
+
+	labels = make([]float64, prob.l)
+	probabilityEstimates = make([][]float64, prob.l)
+	p.runBatch(prob, func(s *predictScratch, i int, px []snode) {
+		if !withProbability {
+			labels[i] = p.predictSnode(s, px, nil)
+			return
+		}
+		decisionValues := make([]float64, p.nrDecisionValues())
+		p.predictSnode(s, px, decisionValues)
+		labels[i], probabilityEstimates[i] = model.probabilityFromDecisionValues(decisionValues)
+	})
+
+	return labels, probabilityEstimates
+}
+
+/**
+ * Calls f for every instance of prob, splitting the instances among the CPUs
+ */
+func (p *Predictor) runBatch(prob *Problem, f func(s *predictScratch, i int, px []snode)) {
+	if prob.l == 0 {
+		return
+	}
+
+	runner := newParallelRunnerLimit(prob.l, p.model.param.Parallelism)
+	runner.run(func(start, end int) {
+		s := p.scratch.Get().(*predictScratch)
+		defer p.scratch.Put(s)
+
+		for i := start; i < end; i++ {
+			f(s, i, prob.xSpace[prob.x[i]:])
+		}
+	})
+	runner.waitAll()
+}
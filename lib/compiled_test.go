@@ -0,0 +1,118 @@
+package libSvm
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPredictorMatchesModel(t *testing.T) {
+	for _, c := range []struct {
+		svmType, kernelType int
+		nrClass             int
+	}{
+		{C_SVC, RBF, 3},
+		{C_SVC, POLY, 2},
+		{EPSILON_SVR, RBF, 0},
+		{ONE_CLASS, RBF, 0},
+	} {
+		prob := newRandomProblem(150, 4, c.svmType == EPSILON_SVR, 4)
+		if c.nrClass == 3 {
+			for i := 0; i < prob.l; i += 3 {
+				prob.y[i] = 2
+			}
+		}
+
+		param := NewParameter()
+		param.SvmType = c.svmType
+		param.KernelType = c.kernelType
+		param.Gamma = 0.3
+		param.Probability = c.svmType == C_SVC
+		param.QuietMode = true
+
+		model := NewModel(param)
+		if err := model.Train(prob); err != nil {
+			t.Fatal(err)
+		}
+		predictor := NewPredictor(model)
+
+		rng := rand.New(rand.NewSource(5))
+		instances := make([]map[int]float64, 50)
+		for i := range instances {
+			instances[i] = map[int]float64{1: rng.NormFloat64(), 3: rng.NormFloat64(), 4: rng.NormFloat64()}
+		}
+
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, x := range instances {
+					wantLabel, wantValues := model.PredictValues(x)
+					gotLabel, gotValues := predictor.PredictValues(x)
+					if gotLabel != wantLabel || len(gotValues) != len(wantValues) {
+						t.Errorf("%s: Predictor.PredictValues = %v %v, want %v %v",
+							svm_type_string[c.svmType], gotLabel, gotValues, wantLabel, wantValues)
+						return
+					}
+					for k := range wantValues {
+						if gotValues[k] != wantValues[k] {
+							t.Errorf("%s: decision value %d = %v, want %v", svm_type_string[c.svmType], k, gotValues[k], wantValues[k])
+						}
+					}
+
+					wantLabel, wantProb := model.PredictProbability(x)
+					gotLabel, gotProb := predictor.PredictProbability(x)
+					if gotLabel != wantLabel || len(gotProb) != len(wantProb) {
+						t.Errorf("%s: Predictor.PredictProbability = %v %v, want %v %v",
+							svm_type_string[c.svmType], gotLabel, gotProb, wantLabel, wantProb)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestPredictBatch(t *testing.T) {
+	prob := newRandomProblem(200, 3, false, 6)
+	for i := 0; i < prob.l; i += 4 {
+		prob.y[i] = 3
+	}
+
+	param := NewParameter()
+	param.Gamma = 0.5
+	param.Probability = true
+	param.Parallelism = 2
+	param.QuietMode = true
+
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+
+	labels, decisionValues := model.PredictBatch(prob)
+	probLabels, probabilityEstimates := model.PredictProbabilityBatch(prob)
+	if len(labels) != prob.l || len(probLabels) != prob.l {
+		t.Fatalf("got %d and %d predictions, want %d", len(labels), len(probLabels), prob.l)
+	}
+
+	var i int = 0
+	for prob.Begin(); !prob.Done(); prob.Next() {
+		_, x := prob.Get()
+		wantLabel, wantValues := model.PredictValues(x)
+		if labels[i] != wantLabel || !reflect.DeepEqual(decisionValues[i], wantValues) {
+			t.Errorf("instance %d: PredictBatch = %v %v, want %v %v", i, labels[i], decisionValues[i], wantLabel, wantValues)
+		}
+		wantLabel, wantProb := model.PredictProbability(x)
+		if probLabels[i] != wantLabel || !reflect.DeepEqual(probabilityEstimates[i], wantProb) {
+			t.Errorf("instance %d: PredictProbabilityBatch = %v %v, want %v %v", i, probLabels[i], probabilityEstimates[i], wantLabel, wantProb)
+		}
+		i++
+	}
+
+	if labels, _ := model.PredictBatch(&Problem{}); len(labels) != 0 {
+		t.Errorf("PredictBatch of an empty problem = %v", labels)
+	}
+}
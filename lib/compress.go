@@ -0,0 +1,56 @@
+package libSvm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh") // followed by the block size '1'-'9' and bzip2Block or bzip2End
+	bzip2Block = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2End   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90} // the stream holds no block
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+/**
+ * Returns true if header starts like a bzip2 stream. "BZh" alone may as well be a string label.
+ */
+func isBzip2(header []byte) bool {
+	n := len(bzip2Magic)
+	if len(header) < n+1+len(bzip2Block) || !bytes.HasPrefix(header, bzip2Magic) {
+		return false
+	}
+	if header[n] < '1' || header[n] > '9' {
+		return false
+	}
+	return bytes.HasPrefix(header[n+1:], bzip2Block) || bytes.HasPrefix(header[n+1:], bzip2End)
+}
+
+/**
+ * Returns a reader that decompresses r if it starts with the magic bytes of a gzip or bzip2 stream,
+ * and r itself (buffered) otherwise. zstd streams are detected but not supported, as there is no
+ * zstd decoder in the Go standard library.
+ */
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(bzip2Magic) + 1 + len(bzip2Block)) // a short read is fine: no header matches
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to read gzip stream: %v", err)
+		}
+		return zr, nil
+	case isBzip2(magic):
+		return bzip2.NewReader(br), nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return nil, fmt.Errorf("zstd compressed input is not supported, decompress it first (e.g. zstd -d)")
+	}
+	return br, nil
+}
@@ -0,0 +1,93 @@
+package libSvm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// "1 1:0.5 3:1\n-1 2:2\n" compressed with bzip2 -9
+var bzip2Problem = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xe0, 0xed,
+	0x7b, 0x83, 0x00, 0x00, 0x04, 0xd8, 0x00, 0x00, 0x10, 0x40, 0x03, 0x7a,
+	0x10, 0x20, 0x00, 0x21, 0xa0, 0x18, 0x84, 0x00, 0xc3, 0x7a, 0x42, 0xb2,
+	0xc5, 0x13, 0x72, 0x11, 0x6f, 0xc5, 0xdc, 0x91, 0x4e, 0x14, 0x24, 0x38,
+	0x3b, 0x5e, 0xe0, 0xc0,
+}
+
+func TestDecompress(t *testing.T) {
+	plain := "1 1:0.5 3:1\n-1 2:2\n"
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(plain))
+	zw.Close()
+
+	want := newTestProblem([]float64{1, -1}, [][]snode{
+		{{index: 1, value: 0.5}, {index: 3, value: 1}},
+		{{index: 2, value: 2}},
+	})
+
+	for name, input := range map[string][]byte{"plain": []byte(plain), "gzip": gz.Bytes(), "bzip2": bzip2Problem} {
+		prob, err := ReadProblem(bytes.NewReader(input))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(prob.y, want.y) || !reflect.DeepEqual(prob.xSpace, want.xSpace) {
+			t.Errorf("%s: ReadProblem = %+v, want %+v", name, prob, want)
+		}
+	}
+
+	_, err := ReadProblem(bytes.NewReader([]byte{0x28, 0xb5, 0x2f, 0xfd, 0}))
+	if err == nil || !strings.Contains(err.Error(), "zstd") {
+		t.Errorf("zstd input: error = %v, want a zstd not supported error", err)
+	}
+
+	if _, err := ReadProblem(bytes.NewReader(nil)); err != nil {
+		t.Errorf("empty input: %v", err)
+	}
+
+	// plain text whose first string label starts with the bzip2 magic
+	for _, text := range []string{"BZh 1:0.5\nham 2:1\n", "BZh9 1:0.5\nham 2:1\n", "BZh91AY 1:0.5\nham 2:1\n"} {
+		prob, err := ReadProblem(strings.NewReader(text))
+		if err != nil {
+			t.Errorf("%q: %v", text, err)
+			continue
+		}
+		if name := strings.Fields(text)[0]; prob.l != 2 || prob.LabelEncoder().Decode(prob.y[0]) != name {
+			t.Errorf("%q: l = %d, first label = %q, want 2 instances and %q", text, prob.l, prob.LabelEncoder().Decode(prob.y[0]), name)
+		}
+	}
+}
+
+func TestDumpGzip(t *testing.T) {
+	prob := newRandomProblem(50, 3, false, 1)
+
+	param := NewParameter()
+	param.QuietMode = true
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, file := range []string{"plain.model", "compressed.model.gz"} {
+		path := filepath.Join(dir, file)
+		if err := model.Dump(path); err != nil {
+			t.Fatal(err)
+		}
+
+		restored := NewModel(NewParameter())
+		if err := restored.ReadModel(path); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		x := map[int]float64{1: 0.3, 2: -0.2, 3: 1}
+		if restored.l != model.l || restored.Predict(x) != model.Predict(x) {
+			t.Errorf("%s: restored model differs, l = %d, want %d", file, restored.l, model.l)
+		}
+	}
+}
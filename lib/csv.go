@@ -0,0 +1,206 @@
+package libSvm
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/**
+ * CSVOptions describes the layout of a CSV dataset: one instance per record with the label in one
+ * column and the features, numbered 1, 2, ... from left to right, in the other columns.
+ */
+type CSVOptions struct {
+	LabelColumn int  // 0-based column of the label; negative values count from the end (-1 is the last column)
+	Header      bool // the first record is a header row
+	Delimiter   rune // field delimiter
+	DropZeros   bool // leave zero values out of the sparse instances (written as 0 in CSV output)
+}
+
+/**
+ * Returns the default CSV options: comma delimited, label in the first column, no header, zeros dropped.
+ */
+func NewCSVOptions() *CSVOptions {
+	return &CSVOptions{LabelColumn: 0, Header: false, Delimiter: ',', DropZeros: true}
+}
+
+func (opts *CSVOptions) labelColumn(nrColumn int) int {
+	if opts.LabelColumn < 0 {
+		return nrColumn + opts.LabelColumn
+	}
+	return opts.LabelColumn
+}
+
+/**
+ * Reads a problem from CSV data. All records must have the same number of fields. Empty fields are
+ * read as zeros. If any label is not a number, all labels are read as strings and encoded with a
+ * LabelEncoder. gzip and bzip2 compressed input is decompressed on the fly. A nil opts means
+ * NewCSVOptions().
+ */
+func ReadCSV(r io.Reader, opts *CSVOptions) (*Problem, error) {
+	if opts == nil {
+		opts = NewCSVOptions()
+	}
+
+	var name string = "input"
+	if named, ok := r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+
+	r, err := decompress(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = opts.Delimiter
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	problem := &Problem{}
+	var rawLabels []string
+	var labelColumn int = -1
+	var first bool = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var csvErr *csv.ParseError
+			if errors.As(err, &csvErr) {
+				return nil, &ParseError{File: name, Line: csvErr.Line, Column: csvErr.Column, Msg: csvErr.Err.Error()}
+			}
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if labelColumn < 0 {
+			if labelColumn = opts.labelColumn(len(record)); labelColumn < 0 || labelColumn >= len(record) {
+				return nil, &ParseError{File: name, Line: line, Column: 1,
+					Msg: fmt.Sprintf("label column %d out of range, the record has %d fields", opts.LabelColumn, len(record))}
+			}
+		}
+		if first && opts.Header {
+			first = false
+			continue
+		}
+		first = false
+
+		parseError := func(field int, format string, a ...interface{}) error {
+			line, column := reader.FieldPos(field)
+			return &ParseError{File: name, Line: line, Column: column, Msg: fmt.Sprintf(format, a...)}
+		}
+
+		problem.x = append(problem.x, len(problem.xSpace))
+		rawLabels = append(rawLabels, strings.TrimSpace(record[labelColumn]))
+
+		var index int = 0
+		for k, field := range record {
+			if k == labelColumn {
+				continue
+			}
+			index++
+
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue // missing values are zeros
+			}
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, parseError(k, "invalid feature value %q", field)
+			}
+			if value == 0 && opts.DropZeros {
+				continue
+			}
+			problem.xSpace = append(problem.xSpace, snode{index: index, value: value})
+		}
+
+		problem.xSpace = append(problem.xSpace, snode{index: -1})
+		problem.l++
+	}
+
+	problem.y, problem.labels = parseLabels(rawLabels)
+
+	return problem, nil
+}
+
+/**
+ * Writes the problem as CSV data with features 1 to MaxIndex() in consecutive columns, absent features
+ * written as 0, and the label in opts.LabelColumn. With opts.Header a header row "label,f1,f2,..." is
+ * written first. A nil opts means NewCSVOptions().
+ */
+func (problem *Problem) WriteCSV(w io.Writer, opts *CSVOptions) error {
+	if opts == nil {
+		opts = NewCSVOptions()
+	}
+
+	nrFeature := problem.MaxIndex()
+	labelColumn := opts.labelColumn(nrFeature + 1)
+	if labelColumn < 0 || labelColumn > nrFeature {
+		return fmt.Errorf("label column %d out of range for %d columns", opts.LabelColumn, nrFeature+1)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.Delimiter
+
+	record := make([]string, nrFeature+1)
+	column := func(index int) int { // column of feature index
+		if index <= labelColumn {
+			return index - 1
+		}
+		return index
+	}
+
+	if opts.Header {
+		record[labelColumn] = "label"
+		for index := 1; index <= nrFeature; index++ {
+			record[column(index)] = fmt.Sprintf("f%d", index)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < problem.l; i++ {
+		for k := range record {
+			record[k] = "0"
+		}
+		record[labelColumn] = problem.labelName(problem.y[i])
+		for idx := problem.x[i]; problem.xSpace[idx].index != -1; idx++ {
+			if index := problem.xSpace[idx].index; index > 0 {
+				record[column(index)] = strconv.FormatFloat(problem.xSpace[idx].value, 'g', -1, 64)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+/**
+ * Writes the problem in LIBSVM format, one "label index:value ..." line per instance, with a "w:weight"
+ * token after the label if the problem has instance weights. Values are written with full precision
+ * so that ReadProblem restores the same problem.
+ */
+func (problem *Problem) WriteLIBSVM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i := 0; i < problem.l; i++ {
+		bw.WriteString(problem.labelName(problem.y[i]))
+		if problem.w != nil {
+			bw.WriteString(" w:" + strconv.FormatFloat(problem.w[i], 'g', -1, 64))
+		}
+		for idx := problem.x[i]; problem.xSpace[idx].index != -1; idx++ {
+			fmt.Fprintf(bw, " %d:%s", problem.xSpace[idx].index, strconv.FormatFloat(problem.xSpace[idx].value, 'g', -1, 64))
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
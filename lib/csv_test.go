@@ -0,0 +1,85 @@
+package libSvm
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "a;label;b;c\n" +
+		"0.5;1;0;2\n" +
+		" ;-1;3;0\n"
+
+	opts := NewCSVOptions()
+	opts.Header = true
+	opts.Delimiter = ';'
+	opts.LabelColumn = 1
+
+	prob, err := ReadCSV(strings.NewReader(input), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := newTestProblem([]float64{1, -1}, [][]snode{
+		{{index: 1, value: 0.5}, {index: 3, value: 2}},
+		{{index: 2, value: 3}},
+	})
+	if !reflect.DeepEqual(prob.y, want.y) || !reflect.DeepEqual(prob.x, want.x) || !reflect.DeepEqual(prob.xSpace, want.xSpace) {
+		t.Errorf("ReadCSV = %+v, want %+v", prob, want)
+	}
+
+	opts = NewCSVOptions()
+	opts.LabelColumn = -1
+	if _, err := ReadCSV(strings.NewReader("1,2\nx,3\n"), opts); err == nil {
+		t.Error("invalid value was accepted")
+	} else {
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != 2 || parseErr.Column != 1 {
+			t.Errorf("error = %v, want a *ParseError at 2:1", err)
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	prob := newTestProblem([]float64{1, -1, 2.5}, [][]snode{
+		{{index: 1, value: 0.1}, {index: 3, value: -2}},
+		{},
+		{{index: 2, value: 1e-20}, {index: 3, value: 7}},
+	})
+
+	for _, labelColumn := range []int{0, 2, -1} {
+		opts := NewCSVOptions()
+		opts.Header = true
+		opts.LabelColumn = labelColumn
+
+		var buf bytes.Buffer
+		if err := prob.WriteCSV(&buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := ReadCSV(&buf, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(restored.y, prob.y) || !reflect.DeepEqual(restored.x, prob.x) || !reflect.DeepEqual(restored.xSpace, prob.xSpace) {
+			t.Errorf("label column %d: CSV round trip = %+v, want %+v", labelColumn, restored, prob)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := prob.WriteLIBSVM(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "1 1:0.1 3:-2\n-1\n2.5 2:1e-20 3:7\n" {
+		t.Errorf("WriteLIBSVM = %q", got)
+	}
+	restored, err := ReadProblem(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.y, prob.y) || !reflect.DeepEqual(restored.xSpace, prob.xSpace) {
+		t.Errorf("LIBSVM round trip = %+v, want %+v", restored, prob)
+	}
+}
@@ -0,0 +1,37 @@
+/**
+ * Package libSvm is a Go port of LIBSVM, a library for support vector
+ * classification (C-SVC, nu-SVC), regression (epsilon-SVR, nu-SVR) and
+ * distribution estimation (one-class SVM).
+ *
+ * A typical session reads a Problem, fills in a Parameter, trains a Model
+ * and then uses it for prediction:
+ *
+ *	param := libSvm.NewParameter()
+ *	var prob libSvm.Problem
+ *	if err := prob.Read("heart_scale", param); err != nil { ... }
+ *	model := libSvm.NewModel(param)
+ *	if err := model.Train(&prob); err != nil { ... }
+ *	label := model.Predict(map[int]float64{1: 0.5, 3: -1})
+ *
+ * Problems can also be built in memory with NewProblemFromDense,
+ * NewProblemFromSparse and Problem.AddInstance. These leave Gamma alone, so
+ * set it explicitly (LIBSVM's default is 1/prob.MaxIndex()).
+ *
+ * Class labels may be strings such as "spam" and "ham": the readers encode
+ * them with a LabelEncoder, the mapping is stored in the model file, and
+ * Model.PredictLabel returns the original label.
+ *
+ * Predictions, e.g. from CrossValidation, are scored with EvaluateClassification
+ * and EvaluateRegression. For two-class problems, CrossValidationDecisionValues
+ * collects out-of-fold decision values for EvaluateROC and EvaluatePR.
+ *
+ * Hyperparameters are tuned by cross validation with GridSearch (like grid.py
+ * and the svm-grid command), RandomSearch or BayesianSearch.
+ *
+ * Cross validation and probability training shuffle the data at random; set
+ * Parameter.Seed to get bit-identical models and scores from identical inputs.
+ *
+ * Models are saved and restored in the LIBSVM model file format with
+ * Model.Dump and Model.ReadModel.
+ */
+package libSvm
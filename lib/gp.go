@@ -0,0 +1,173 @@
+package libSvm
+
+import (
+	"math"
+)
+
+/**
+ * gaussianProcess is a Gaussian-process regression model with a squared exponential kernel on
+ * points of the unit cube, the surrogate of BayesianSearch. The targets are standardized, and the
+ * length scale and noise are chosen by maximizing the marginal likelihood over a small set of values.
+ */
+type gaussianProcess struct {
+	x           [][]float64
+	alpha       []float64   // K^-1 (y - mean) / std
+	chol        [][]float64 // lower Cholesky factor of K
+	lengthScale float64
+	noise       float64 // noise variance, relative to the standardized targets
+	mean        float64
+	std         float64
+}
+
+var gp_length_scales = []float64{0.05, 0.1, 0.2, 0.4, 0.8}
+var gp_noises = []float64{1e-4, 1e-2, 1e-1}
+
+func fitGaussianProcess(x [][]float64, y []float64) *gaussianProcess {
+	var n int = len(y)
+
+	var mean, std float64 = 0, 0
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(n)
+	for _, v := range y {
+		std += (v - mean) * (v - mean)
+	}
+	std = math.Sqrt(std / float64(n))
+	if std == 0 {
+		std = 1
+	}
+
+	z := make([]float64, n)
+	for i, v := range y {
+		z[i] = (v - mean) / std
+	}
+
+	var best *gaussianProcess
+	var bestLikelihood float64 = math.Inf(-1)
+	for _, lengthScale := range gp_length_scales {
+		for _, noise := range gp_noises {
+			gp := &gaussianProcess{x: x, lengthScale: lengthScale, noise: noise, mean: mean, std: std}
+
+			gp.chol = make([][]float64, n)
+			for i := 0; i < n; i++ {
+				gp.chol[i] = make([]float64, n)
+				for j := 0; j <= i; j++ {
+					gp.chol[i][j] = gp.kernel(x[i], x[j])
+				}
+				gp.chol[i][i] += noise
+			}
+			if !cholesky(gp.chol) {
+				continue
+			}
+			gp.alpha = solveUpper(gp.chol, solveLower(gp.chol, z))
+
+			// log marginal likelihood, up to a constant
+			var likelihood float64 = 0
+			for i := 0; i < n; i++ {
+				likelihood -= 0.5*z[i]*gp.alpha[i] + math.Log(gp.chol[i][i])
+			}
+			if likelihood > bestLikelihood {
+				best, bestLikelihood = gp, likelihood
+			}
+		}
+	}
+
+	return best
+}
+
+func (gp *gaussianProcess) kernel(a, b []float64) float64 {
+	var d2 float64 = 0
+	for k := range a {
+		d2 += (a[k] - b[k]) * (a[k] - b[k])
+	}
+	return math.Exp(-d2 / (2 * gp.lengthScale * gp.lengthScale))
+}
+
+/**
+ * Returns the predicted mean and standard deviation at x
+ */
+func (gp *gaussianProcess) predict(x []float64) (float64, float64) {
+	k := make([]float64, len(gp.x))
+	var mu float64 = 0
+	for i := range gp.x {
+		k[i] = gp.kernel(x, gp.x[i])
+		mu += k[i] * gp.alpha[i]
+	}
+
+	v := solveLower(gp.chol, k)
+	var variance float64 = 1
+	for i := range v {
+		variance -= v[i] * v[i]
+	}
+
+	return gp.mean + gp.std*mu, gp.std * math.Sqrt(maxf(variance, 1e-12))
+}
+
+/**
+ * Returns the expected improvement of x over best, for maximization
+ */
+func (gp *gaussianProcess) expectedImprovement(x []float64, best float64) float64 {
+	mu, sigma := gp.predict(x)
+	improvement := mu - best - 0.01*gp.std
+	z := improvement / sigma
+	cdf := 0.5 * math.Erfc(-z/math.Sqrt2)
+	pdf := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+	return improvement*cdf + sigma*pdf
+}
+
+/**
+ * Replaces the lower triangle of the symmetric positive definite matrix a by its Cholesky factor.
+ * Returns false if a is not positive definite.
+ */
+func cholesky(a [][]float64) bool {
+	for j := range a {
+		sum := a[j][j]
+		for k := 0; k < j; k++ {
+			sum -= a[j][k] * a[j][k]
+		}
+		if sum <= 0 {
+			return false
+		}
+		a[j][j] = math.Sqrt(sum)
+
+		for i := j + 1; i < len(a); i++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= a[i][k] * a[j][k]
+			}
+			a[i][j] = sum / a[j][j]
+		}
+	}
+	return true
+}
+
+/**
+ * Solves L x = b for the lower triangular L
+ */
+func solveLower(l [][]float64, b []float64) []float64 {
+	x := make([]float64, len(b))
+	for i := range b {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i][k] * x[k]
+		}
+		x[i] = sum / l[i][i]
+	}
+	return x
+}
+
+/**
+ * Solves L^T x = b for the lower triangular L
+ */
+func solveUpper(l [][]float64, b []float64) []float64 {
+	x := make([]float64, len(b))
+	for i := len(b) - 1; i >= 0; i-- {
+		sum := b[i]
+		for k := i + 1; k < len(b); k++ {
+			sum -= l[k][i] * x[k]
+		}
+		x[i] = sum / l[i][i]
+	}
+	return x
+}
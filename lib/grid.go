@@ -0,0 +1,445 @@
+package libSvm
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+ * GridSearch scores every combination of the given hyperparameter values by cross validation,
+ * like LIBSVM's grid.py. An axis with no values keeps the value of Param.
+ */
+type GridSearch struct {
+	Param  *Parameter // base parameters; C, Gamma, Nu and P are replaced at each grid point
+	NrFold int        // number of cross-validation folds
+
+	Log2C     []float64 // exponents of C
+	Log2Gamma []float64 // exponents of Gamma
+	Nu        []float64 // values of Nu, for NU_SVC, ONE_CLASS and NU_SVR
+	Log2P     []float64 // exponents of P, for EPSILON_SVR
+
+	Previous []GridPoint // already scored points, e.g. from ReadGridResults; they are not evaluated again
+	Results  io.Writer   // if not nil, receives a grid.py style line for each newly scored point
+}
+
+/**
+ * GridPoint is a point of the grid and its cross-validation score. Axes that are not searched hold the
+ * value of the base parameters.
+ */
+type GridPoint struct {
+	Log2C     float64
+	Log2Gamma float64
+	Nu        float64
+	Log2P     float64
+	Score     float64 // accuracy in percent for classification, mean squared error for regression; NaN if the training failed
+}
+
+/**
+ * GridResult holds the scores of all the points of a grid search, in grid order (the last axis varies
+ * fastest), and the best of them.
+ */
+type GridResult struct {
+	Points    []GridPoint
+	Best      GridPoint
+	BestParam *Parameter // Param with the values of Best
+
+	axes []int // the searched axes, for WriteCSV
+}
+
+const (
+	GRID_LOG2C     = iota
+	GRID_LOG2GAMMA = iota
+	GRID_NU        = iota
+	GRID_LOG2P     = iota
+)
+
+var grid_axis_string = []string{"log2c", "log2g", "nu", "log2p"}
+
+/**
+ * Returns a grid search with grid.py's default ranges: log2(C) from -5 to 15 and log2(gamma) from 3
+ * to -15 in steps of 2, plus log2(p) from -8 to -1 for EPSILON_SVR. C is not searched for NU_SVC and
+ * ONE_CLASS, which do not use it, nor gamma for the linear kernel.
+ */
+func NewGridSearch(param *Parameter) *GridSearch {
+	g := &GridSearch{Param: param, NrFold: 5}
+
+	if param.SvmType != NU_SVC && param.SvmType != ONE_CLASS {
+		g.Log2C = GridRange(-5, 15, 2)
+	}
+	if param.KernelType != LINEAR && param.KernelType != PRECOMPUTED {
+		g.Log2Gamma = GridRange(3, -15, -2)
+	}
+	if param.SvmType == EPSILON_SVR {
+		g.Log2P = GridRange(-8, -1, 1)
+	}
+
+	return g
+}
+
+/**
+ * Returns begin, begin+step, ... up to and including end, like grid.py's range options.
+ */
+func GridRange(begin, end, step float64) []float64 {
+	var values []float64
+	if step == 0 || (end-begin)/step < 0 {
+		return []float64{begin}
+	}
+	n := int(math.Floor((end-begin)/step + 1e-9))
+	for i := 0; i <= n; i++ {
+		values = append(values, begin+float64(i)*step)
+	}
+	return values
+}
+
+func (p *GridPoint) axis(a int) *float64 {
+	switch a {
+	case GRID_LOG2C:
+		return &p.Log2C
+	case GRID_LOG2GAMMA:
+		return &p.Log2Gamma
+	case GRID_NU:
+		return &p.Nu
+	}
+	return &p.Log2P
+}
+
+func (g *GridSearch) axisValues(a int) []float64 {
+	switch a {
+	case GRID_LOG2C:
+		return g.Log2C
+	case GRID_LOG2GAMMA:
+		return g.Log2Gamma
+	case GRID_NU:
+		return g.Nu
+	}
+	return g.Log2P
+}
+
+/**
+ * Returns a copy of param with the values of the point on the searched axes
+ */
+func (p GridPoint) apply(param *Parameter, axes []int) *Parameter {
+	pointParam := *param
+	for _, a := range axes {
+		switch a {
+		case GRID_LOG2C:
+			pointParam.C = math.Exp2(p.Log2C)
+		case GRID_LOG2GAMMA:
+			pointParam.Gamma = math.Exp2(p.Log2Gamma)
+		case GRID_NU:
+			pointParam.Nu = p.Nu
+		case GRID_LOG2P:
+			pointParam.P = math.Exp2(p.Log2P)
+		}
+	}
+	return &pointParam
+}
+
+/**
+ * Returns the point as a line of a grid.py results file, e.g. "log2c=3 log2g=-5 rate=84.5"
+ */
+func (p *GridPoint) format(axes []int) string {
+	var b strings.Builder
+	for _, a := range axes {
+		fmt.Fprintf(&b, "%s=%s ", grid_axis_string[a], strconv.FormatFloat(*p.axis(a), 'g', -1, 64))
+	}
+	fmt.Fprintf(&b, "rate=%s", strconv.FormatFloat(p.Score, 'g', -1, 64))
+	return b.String()
+}
+
+/**
+ * Same as RunContext with a context that is never cancelled.
+ */
+func (g *GridSearch) Run(prob *Problem) (*GridResult, error) {
+	return g.RunContext(context.Background(), prob)
+}
+
+/**
+ * Scores every point of the grid by cross validation on prob, using at most Param.Parallelism
+ * concurrent cross validations (one per CPU if 0), and returns the scores and the best point.
+ * Ties are broken in favour of the smaller C. With more than one concurrent cross validation, each
+ * trains on a single goroutine and the calls to Param.Logger and Param.Progress are serialised.
+ *
+ * A point whose training fails, e.g. because nu is infeasible, scores NaN; an error is returned only
+ * if every point fails. When ctx is cancelled the points scored so far are returned with ctx's error.
+ */
+func (g *GridSearch) RunContext(ctx context.Context, prob *Problem) (*GridResult, error) {
+	param := g.Param
+	if g.NrFold < 2 {
+		return nil, fmt.Errorf("Fail to run grid search: number of folds %d must be >= 2\n", g.NrFold)
+	}
+
+	base := GridPoint{Log2C: math.Log2(param.C), Log2Gamma: math.Log2(param.Gamma), Nu: param.Nu, Log2P: math.Log2(param.P)}
+	result := &GridResult{}
+	for a := range grid_axis_string {
+		if len(g.axisValues(a)) > 0 {
+			result.axes = append(result.axes, a)
+		}
+	}
+
+	// enumerate the grid, the last axis varying fastest
+	result.Points = []GridPoint{base}
+	for _, a := range result.axes {
+		var points []GridPoint
+		for _, p := range result.Points {
+			for _, v := range g.axisValues(a) {
+				*p.axis(a) = v
+				points = append(points, p)
+			}
+		}
+		result.Points = points
+	}
+
+	var todo []int
+	for i := range result.Points {
+		result.Points[i].Score = math.NaN()
+		if score, ok := g.previousScore(&result.Points[i], result.axes); ok {
+			result.Points[i].Score = score
+		} else {
+			todo = append(todo, i)
+		}
+	}
+
+	workers := param.Parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	workerParam := concurrentParameter(param, workers)
+
+	var mu sync.Mutex // guards firstErr and Results
+	var firstErr, writeErr error
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				p := &result.Points[i]
+				score, err := crossValidationScore(ctx, prob, p.apply(workerParam, result.axes), g.NrFold)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					p.Score = score
+					if g.Results != nil && writeErr == nil {
+						_, writeErr = fmt.Fprintln(g.Results, p.format(result.axes))
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, i := range todo {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	regression := param.SvmType == EPSILON_SVR || param.SvmType == NU_SVR
+	var found bool = false
+	for _, p := range result.Points {
+		if math.IsNaN(p.Score) {
+			continue
+		}
+		better := p.Score > result.Best.Score
+		if regression {
+			better = p.Score < result.Best.Score
+		}
+		if !found || better || (p.Score == result.Best.Score && p.Log2C < result.Best.Log2C) {
+			result.Best = p
+			found = true
+		}
+	}
+	if found {
+		result.BestParam = result.Best.apply(param, result.axes)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if !found {
+		return result, firstErr
+	}
+	return result, writeErr
+}
+
+/**
+ * Returns the score of the point in g.Previous that matches p on the searched axes
+ */
+func (g *GridSearch) previousScore(p *GridPoint, axes []int) (float64, bool) {
+	for i := range g.Previous {
+		prev := &g.Previous[i]
+		var match bool = true
+		for _, a := range axes {
+			if math.Abs(*prev.axis(a)-*p.axis(a)) > 1e-9 {
+				match = false
+				break
+			}
+		}
+		if match {
+			return prev.Score, true
+		}
+	}
+	return 0, false
+}
+
+/**
+ * Returns a copy of param for cross validations run by workers goroutines: the solvers of each run on a
+ * single goroutine, so that the workers do not oversubscribe the CPUs, and the calls to Logger and
+ * Progress are serialised. param itself is returned for a single worker.
+ */
+func concurrentParameter(param *Parameter, workers int) *Parameter {
+	if workers <= 1 {
+		return param
+	}
+
+	workerParam := *param
+	workerParam.Parallelism = 1
+
+	var mu sync.Mutex // guards the callbacks
+	if logger := param.Logger; logger != nil {
+		workerParam.Logger = func(msg string) {
+			mu.Lock()
+			defer mu.Unlock()
+			logger(msg)
+		}
+	}
+	if progress := param.Progress; progress != nil {
+		workerParam.Progress = func(p SolverProgress) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return progress(p)
+		}
+	}
+	return &workerParam
+}
+
+/**
+ * Returns the cross-validation accuracy in percent, or the mean squared error for regression
+ */
+func crossValidationScore(ctx context.Context, prob *Problem, param *Parameter, nrFold int) (float64, error) {
+	if err := param.Validate(prob); err != nil {
+		return 0, err
+	}
+
+	target, err := CrossValidationContext(ctx, prob, param, nrFold)
+	if err != nil {
+		return 0, err
+	}
+
+	if param.SvmType == EPSILON_SVR || param.SvmType == NU_SVR {
+		metrics, err := EvaluateRegression(prob, target)
+		if err != nil {
+			return 0, err
+		}
+		return metrics.MSE, nil
+	}
+
+	metrics, err := EvaluateClassification(prob, target)
+	if err != nil {
+		return 0, err
+	}
+	return 100 * metrics.Accuracy, nil
+}
+
+/**
+ * Reads the points of a grid.py style results file, one "log2c=3 log2g=-5 rate=84.5" line per point,
+ * e.g. to resume an interrupted search through GridSearch.Previous. Axes missing from a line keep 0.
+ */
+func ReadGridResults(r io.Reader) ([]GridPoint, error) {
+	var points []GridPoint
+
+	scanner := bufio.NewScanner(r)
+	var lineNum int = 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var p GridPoint
+		var hasRate bool = false
+		for _, field := range strings.Fields(line) {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("Fail to read grid results: line %d: invalid field %q\n", lineNum, field)
+			}
+			value, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("Fail to read grid results: line %d: invalid value %q\n", lineNum, kv[1])
+			}
+
+			if kv[0] == "rate" {
+				p.Score = value
+				hasRate = true
+				continue
+			}
+			var a int
+			for a = range grid_axis_string {
+				if grid_axis_string[a] == kv[0] {
+					break
+				}
+			}
+			if grid_axis_string[a] != kv[0] {
+				return nil, fmt.Errorf("Fail to read grid results: line %d: unknown parameter %q\n", lineNum, kv[0])
+			}
+			*p.axis(a) = value
+		}
+		if !hasRate {
+			return nil, fmt.Errorf("Fail to read grid results: line %d: missing rate\n", lineNum)
+		}
+
+		points = append(points, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+/**
+ * Writes the scores as CSV, one row per point in grid order with a column for each searched axis
+ * followed by "score", e.g. "log2c,log2g,score". Rows are ordered for contour plotting: the last axis
+ * varies fastest.
+ */
+func (r *GridResult) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	record := make([]string, 0, len(r.axes)+1)
+	for _, a := range r.axes {
+		record = append(record, grid_axis_string[a])
+	}
+	writer.Write(append(record, "score"))
+
+	for i := range r.Points {
+		record = record[:0]
+		for _, a := range r.axes {
+			record = append(record, strconv.FormatFloat(*r.Points[i].axis(a), 'g', -1, 64))
+		}
+		writer.Write(append(record, strconv.FormatFloat(r.Points[i].Score, 'g', -1, 64)))
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
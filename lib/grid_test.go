@@ -0,0 +1,178 @@
+package libSvm
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGridRange(t *testing.T) {
+	if got := GridRange(-5, 15, 2); len(got) != 11 || got[0] != -5 || got[10] != 15 {
+		t.Errorf("GridRange(-5, 15, 2) = %v", got)
+	}
+	if got := GridRange(3, -15, -2); len(got) != 10 || got[9] != -15 {
+		t.Errorf("GridRange(3, -15, -2) = %v", got)
+	}
+	if got := GridRange(0.1, 0.5, 0.2); !reflect.DeepEqual(got, []float64{0.1, 0.30000000000000004, 0.5}) {
+		t.Errorf("GridRange(0.1, 0.5, 0.2) = %v", got)
+	}
+}
+
+func TestGridSearch(t *testing.T) {
+	prob := newRandomProblem(80, 4, false, 3)
+
+	param := NewParameter()
+	param.QuietMode = true
+	param.Parallelism = 3
+
+	g := NewGridSearch(param)
+	g.Log2C = []float64{-3, 1, 5}
+	g.Log2Gamma = []float64{-5, -1}
+	var results bytes.Buffer
+	g.Results = &results
+
+	result, err := g.Run(prob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != 6 {
+		t.Fatalf("%d points, want 6", len(result.Points))
+	}
+	if p := result.Points[1]; p.Log2C != -3 || p.Log2Gamma != -1 {
+		t.Errorf("second point = %+v, want log2c=-3 log2g=-1", p)
+	}
+	for _, p := range result.Points {
+		if math.IsNaN(p.Score) || p.Score > result.Best.Score {
+			t.Errorf("point %+v, best %+v", p, result.Best)
+		}
+	}
+	if result.BestParam.C != math.Exp2(result.Best.Log2C) || result.BestParam.Gamma != math.Exp2(result.Best.Log2Gamma) {
+		t.Errorf("best parameters C=%g gamma=%g for %+v", result.BestParam.C, result.BestParam.Gamma, result.Best)
+	}
+	if param.C != 1 || param.Gamma != 0 {
+		t.Errorf("base parameters were modified: C=%g gamma=%g", param.C, param.Gamma)
+	}
+
+	// resuming from the results file scores nothing again
+	previous, err := ReadGridResults(&results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previous) != 6 {
+		t.Fatalf("read %d results, want 6", len(previous))
+	}
+	for i := range previous { // make the first point the best; the results are in completion order
+		if previous[i].Log2C == -3 && previous[i].Log2Gamma == -5 {
+			previous[i].Score = 101
+		}
+	}
+	g.Previous = previous
+	g.Results = nil
+	resumed, err := g.Run(prob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Best.Log2C != -3 || resumed.Best.Log2Gamma != -5 || resumed.Best.Score != 101 {
+		t.Errorf("resumed best = %+v", resumed.Best)
+	}
+
+	var buf bytes.Buffer
+	if err := resumed.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 7 || lines[0] != "log2c,log2g,score" || lines[1] != "-3,-5,101" {
+		t.Errorf("csv =\n%s", buf.String())
+	}
+}
+
+func TestGridSearchRegression(t *testing.T) {
+	prob := newRandomProblem(60, 3, true, 4)
+
+	param := NewParameter()
+	param.SvmType = EPSILON_SVR
+	param.KernelType = LINEAR
+	param.QuietMode = true
+
+	g := NewGridSearch(param)
+	if g.Log2Gamma != nil || len(g.Log2P) != 8 {
+		t.Errorf("default grid: log2g %v log2p %v", g.Log2Gamma, g.Log2P)
+	}
+	g.Log2C = []float64{-8, 2}
+	g.Log2P = []float64{-4, 3}
+
+	result, err := g.Run(prob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range result.Points {
+		if p.Score < result.Best.Score {
+			t.Errorf("point %+v has a smaller MSE than best %+v", p, result.Best)
+		}
+	}
+	if result.Best.Log2C != 2 || result.Best.Log2P != -4 {
+		t.Errorf("best = %+v, want log2c=2 log2p=-4", result.Best)
+	}
+
+	// an infeasible parameter at every point is reported
+	param.SvmType = NU_SVR
+	g = &GridSearch{Param: param, NrFold: 5, Nu: []float64{1.5}}
+	if _, err := g.Run(prob); err == nil {
+		t.Error("expected an error for nu > 1")
+	}
+}
+
+func TestGridSearchCallbacks(t *testing.T) {
+	prob := newRandomProblem(80, 3, false, 5)
+
+	// the callbacks are not goroutine-safe; the grid search must not call them concurrently
+	var active, calls int32
+	enter := func() {
+		if atomic.AddInt32(&active, 1) > 1 {
+			t.Error("callback called concurrently")
+		}
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Microsecond) // give another call the time to overlap
+		atomic.AddInt32(&active, -1)
+	}
+
+	param := NewParameter()
+	param.Parallelism = 4
+	param.Logger = func(string) { enter() }
+	param.Progress = func(SolverProgress) bool { enter(); return true }
+
+	g := NewGridSearch(param)
+	g.Log2C = []float64{-1, 1, 3, 5}
+	g.Log2Gamma = []float64{-3, -1}
+	result, err := g.Run(prob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 {
+		t.Error("no callback was called")
+	}
+	if result.BestParam.Parallelism != 4 {
+		t.Errorf("best parameters have Parallelism %d, want the base parameters' 4", result.BestParam.Parallelism)
+	}
+}
+
+func TestReadGridResults(t *testing.T) {
+	points, err := ReadGridResults(strings.NewReader("log2c=5 log2g=-7 rate=82.5\n\nnu=0.25 rate=90\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GridPoint{{Log2C: 5, Log2Gamma: -7, Score: 82.5}, {Nu: 0.25, Score: 90}}
+	if !reflect.DeepEqual(points, want) {
+		t.Errorf("points = %+v, want %+v", points, want)
+	}
+
+	for _, input := range []string{"log2c=5 log2g=-7\n", "log2c=x rate=1\n", "cost=1 rate=1\n"} {
+		if _, err := ReadGridResults(strings.NewReader(input)); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package libSvm
 
 import (
 	"errors"
@@ -10,6 +10,7 @@ Interface for all kernel functions
 */
 type kernelFunction interface {
 	compute(i, j int) float64
+	swapIndex(i, j int) // swaps SVs i and j, used when shrinking
 }
 
 /**
@@ -47,6 +48,10 @@ func (k linear) compute(i, j int) float64 {
 	return dot(k.xSpace[idx_i:], k.xSpace[idx_j:])
 }
 
+func (k linear) swapIndex(i, j int) {
+	k.x[i], k.x[j] = k.x[j], k.x[i]
+}
+
 func NewLinear(x []int, xSpace []snode) linear {
 	return linear{x: x, xSpace: xSpace}
 }
@@ -66,6 +71,11 @@ func (k rbf) compute(i, j int) float64 {
 	return math.Exp(-k.gamma * q)
 }
 
+func (k rbf) swapIndex(i, j int) {
+	k.x[i], k.x[j] = k.x[j], k.x[i]
+	k.x_square[i], k.x_square[j] = k.x_square[j], k.x_square[i]
+}
+
 func NewRBF(x []int, xSpace []snode, l int, gamma float64) rbf {
 	x_square := make([]float64, l)
 
@@ -93,6 +103,10 @@ func (k poly) compute(i, j int) float64 {
 	return math.Pow(q, float64(k.degree))
 }
 
+func (k poly) swapIndex(i, j int) {
+	k.x[i], k.x[j] = k.x[j], k.x[i]
+}
+
 func NewPoly(x []int, xSpace []snode, gamma, coef0 float64, degree int) poly {
 	return poly{x: x, xSpace: xSpace, gamma: gamma, coef0: coef0, degree: degree}
 }
@@ -112,21 +126,51 @@ func (k sigmoid) compute(i, j int) float64 {
 	return math.Tanh(q)
 }
 
+func (k sigmoid) swapIndex(i, j int) {
+	k.x[i], k.x[j] = k.x[j], k.x[i]
+}
+
 func NewSigmoid(x []int, xSpace []snode, gamma, coef0 float64) sigmoid {
 	return sigmoid{x: x, xSpace: xSpace, gamma: gamma, coef0: coef0}
 }
 
+/************** PRECOMPUTED KERNEL *************/
+type precomputed struct {
+	x      []int
+	xSpace []snode
+}
+
+func (k precomputed) compute(i, j int) float64 {
+	var idx_i int = k.x[i]
+	var idx_j int = k.x[j]
+	serial_j := int(k.xSpace[idx_j].value) // 0:serial_number is the first node of every instance
+	return k.xSpace[idx_i+serial_j].value
+}
+
+func (k precomputed) swapIndex(i, j int) {
+	k.x[i], k.x[j] = k.x[j], k.x[i]
+}
+
+func NewPrecomputed(x []int, xSpace []snode) precomputed {
+	return precomputed{x: x, xSpace: xSpace}
+}
+
 /************** Factory ***************/
 func NewKernel(prob *Problem, param *Parameter) (kernelFunction, error) {
+	x := make([]int, prob.l) // the kernel gets its own copy since swapIndex() reorders it
+	copy(x, prob.x)
+
 	switch param.KernelType {
 	case LINEAR:
-		return NewLinear(prob.x, prob.xSpace), nil
+		return NewLinear(x, prob.xSpace), nil
 	case POLY:
-		return NewPoly(prob.x, prob.xSpace, param.Gamma, param.Coef0, param.Degree), nil
+		return NewPoly(x, prob.xSpace, param.Gamma, param.Coef0, param.Degree), nil
 	case RBF:
-		return NewRBF(prob.x, prob.xSpace, prob.l, param.Gamma), nil
+		return NewRBF(x, prob.xSpace, prob.l, param.Gamma), nil
 	case SIGMOID:
-		return NewSigmoid(prob.x, prob.xSpace, param.Gamma, param.Coef0), nil
+		return NewSigmoid(x, prob.xSpace, param.Gamma, param.Coef0), nil
+	case PRECOMPUTED:
+		return NewPrecomputed(x, prob.xSpace), nil
 	}
 	return nil, errors.New("unsupported kernel")
 }
@@ -145,8 +189,14 @@ func computeKernelValue(px, py []snode, param *Parameter) float64 {
 		q := param.Gamma*dot(px, py) + param.Coef0
 		return math.Tanh(q)
 	case PRECOMPUTED:
-		var idx_j int = int(py[0].value)
-		return px[idx_j].value
+		var idx_j int = int(py[0].value) // serial number of the SV
+		// px may be a row of a shared xSpace, so it must not be indexed past its -1 terminator
+		for i := 0; px[i].index != -1 && px[i].index <= idx_j; i++ {
+			if px[i].index == idx_j {
+				return px[i].value
+			}
+		}
+		return 0
 	}
 
 	return 0
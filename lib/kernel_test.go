@@ -1,4 +1,4 @@
-package main
+package libSvm
 
 import (
 	"fmt"
@@ -8,3 +8,63 @@ import (
 func TestLinear(t *testing.T) {
 	fmt.Printf("Hello from my first test\n")
 }
+
+func TestPrecomputed(t *testing.T) {
+	rows := [][]snode{
+		{{index: 1, value: 1}, {index: 2, value: 2}},
+		{{index: 1, value: -1}},
+		{{index: 2, value: 3}},
+	}
+	prob := newTestProblem([]float64{1, -1, 1}, rows)
+
+	var pre [][]snode
+	for i := range rows {
+		row := []snode{{index: 0, value: float64(i + 1)}}
+		for j := range rows {
+			row = append(row, snode{index: j + 1, value: dot(prob.xSpace[prob.x[i]:], prob.xSpace[prob.x[j]:])})
+		}
+		pre = append(pre, row)
+	}
+	preProb := newTestProblem([]float64{1, -1, 1}, pre)
+	if err := preProb.checkPrecomputed(); err != nil {
+		t.Fatal(err)
+	}
+
+	linearKernel := NewLinear(prob.x, prob.xSpace)
+	preKernel := NewPrecomputed(preProb.x, preProb.xSpace)
+	param := &Parameter{KernelType: PRECOMPUTED}
+	for i := range rows {
+		for j := range rows {
+			if got, want := preKernel.compute(i, j), linearKernel.compute(i, j); got != want {
+				t.Errorf("K(%d,%d) = %v, want %v", i, j, got, want)
+			}
+			px := preProb.xSpace[preProb.x[i]:]
+			py := preProb.xSpace[preProb.x[j]:]
+			if got, want := computeKernelValue(px, py, param), linearKernel.compute(i, j); got != want {
+				t.Errorf("computeKernelValue(%d,%d) = %v, want %v", i, j, got, want)
+			}
+		}
+	}
+
+	pre[2] = pre[2][:2] // kernel values of serial numbers 2 and 3 missing
+	if err := newTestProblem([]float64{1, -1, 1}, pre).checkPrecomputed(); err == nil {
+		t.Errorf("expected an error for a truncated kernel row")
+	}
+}
+
+func TestPrecomputedShortRow(t *testing.T) {
+	// the first row has no kernel value for serial number 4; the node of the next row that
+	// follows it in xSpace must not be taken for it
+	pre := [][]snode{
+		{{index: 0, value: 1}, {index: 1, value: 0.5}},
+		{{index: 0, value: 2}, {index: 4, value: 9}},
+	}
+	prob := newTestProblem([]float64{1, -1}, pre)
+	param := &Parameter{KernelType: PRECOMPUTED}
+
+	px := prob.xSpace[prob.x[0]:]
+	py := []snode{{index: 0, value: 4}, {index: -1}}
+	if got := computeKernelValue(px, py, param); got != 0 {
+		t.Errorf("computeKernelValue = %v, want 0", got)
+	}
+}
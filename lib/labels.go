@@ -0,0 +1,134 @@
+package libSvm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+ * LabelEncoder maps string class labels, e.g. "spam" and "ham", to the numeric labels used for training:
+ * the i-th distinct label is encoded as i.
+ */
+type LabelEncoder struct {
+	names []string       // names[code] is the label encoded as code
+	codes map[string]int // inverse of names
+}
+
+/**
+ * Returns an empty label encoder.
+ */
+func NewLabelEncoder() *LabelEncoder {
+	return &LabelEncoder{codes: make(map[string]int)}
+}
+
+/**
+ * Returns the numeric label of name, assigning the next free code if name is new.
+ */
+func (e *LabelEncoder) Encode(name string) float64 {
+	code, ok := e.codes[name]
+	if !ok {
+		code = len(e.names)
+		e.set(code, name)
+	}
+	return float64(code)
+}
+
+func (e *LabelEncoder) set(code int, name string) {
+	for len(e.names) <= code {
+		e.names = append(e.names, "")
+	}
+	e.names[code] = name
+	e.codes[name] = code
+}
+
+/**
+ * Returns the numeric label of name and whether name is known, without assigning a new code.
+ */
+func (e *LabelEncoder) Code(name string) (float64, bool) {
+	code, ok := e.codes[name]
+	return float64(code), ok
+}
+
+/**
+ * Returns the string label encoded as y. Values that are not a code of the encoder are formatted as numbers.
+ */
+func (e *LabelEncoder) Decode(y float64) string {
+	if code := int(y); float64(code) == y && code >= 0 && code < len(e.names) {
+		return e.names[code]
+	}
+	return formatLabel(y)
+}
+
+/**
+ * Returns the known labels in the order of their codes.
+ */
+func (e *LabelEncoder) Names() []string {
+	return append([]string(nil), e.names...)
+}
+
+func (e *LabelEncoder) clone() *LabelEncoder {
+	c := NewLabelEncoder()
+	for code, name := range e.names {
+		c.set(code, name)
+	}
+	return c
+}
+
+func formatLabel(y float64) string {
+	return strconv.FormatFloat(y, 'g', -1, 64)
+}
+
+/**
+ * Converts the labels read from a file. If they are all numbers they are used as they are; otherwise
+ * every label, numeric looking or not, is treated as a string and encoded with a new LabelEncoder.
+ */
+func parseLabels(raw []string) ([]float64, *LabelEncoder) {
+	y := make([]float64, len(raw))
+
+	var numeric bool = true
+	for i, s := range raw {
+		var err error
+		if y[i], err = strconv.ParseFloat(s, 64); err != nil {
+			numeric = false
+			break
+		}
+	}
+	if numeric {
+		return y, nil
+	}
+
+	labels := NewLabelEncoder()
+	for i, s := range raw {
+		y[i] = labels.Encode(s)
+	}
+	return y, labels
+}
+
+/**
+ * Parses the quoted names of a "label_names" model file line
+ */
+func parseLabelNames(line string) ([]string, error) {
+	var names []string
+	line = strings.TrimSpace(line)
+	for len(line) > 0 {
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to parse label names from %q\n", line)
+		}
+		name, _ := strconv.Unquote(quoted)
+		names = append(names, name)
+		line = strings.TrimSpace(line[len(quoted):])
+	}
+	return names, nil
+}
+
+/**
+ * Returns the original label of y
+ */
+func (problem *Problem) labelName(y float64) string {
+	if problem.labels != nil {
+		return problem.labels.Decode(y)
+	}
+	return formatLabel(y)
+}
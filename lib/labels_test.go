@@ -0,0 +1,151 @@
+package libSvm
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringLabels(t *testing.T) {
+	input := "spam 1:1 2:0.9\n" +
+		"ham 1:-1 2:-0.8\n" +
+		"spam 1:0.8 2:1\n" +
+		"ham 1:-0.9 2:-1\n" +
+		"eggs 3:1\n" +
+		"eggs 3:0.9\n"
+
+	prob, err := ReadProblem(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prob.LabelEncoder() == nil || !reflect.DeepEqual(prob.LabelEncoder().Names(), []string{"spam", "ham", "eggs"}) {
+		t.Fatalf("label encoder = %+v, want spam, ham, eggs", prob.LabelEncoder())
+	}
+	if !reflect.DeepEqual(prob.y, []float64{0, 1, 0, 1, 2, 2}) {
+		t.Errorf("encoded labels = %v", prob.y)
+	}
+	if code, ok := prob.LabelEncoder().Code("eggs"); !ok || code != 2 {
+		t.Errorf("Code(eggs) = %v, %v, want 2, true", code, ok)
+	}
+	if _, ok := prob.LabelEncoder().Code("bacon"); ok || len(prob.LabelEncoder().Names()) != 3 {
+		t.Errorf("Code(bacon) found or assigned a code")
+	}
+
+	param := NewParameter()
+	param.Gamma = 0.5
+	param.C = 10
+	param.Probability = true
+	param.QuietMode = true
+
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "model")
+	if err := model.Dump(file); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewModel(NewParameter())
+	if err := restored.ReadModel(file); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []*Model{model, restored} {
+		if !reflect.DeepEqual(m.LabelNames(), []string{"spam", "ham", "eggs"}) {
+			t.Errorf("LabelNames() = %v", m.LabelNames())
+		}
+		if got := m.PredictLabel(map[int]float64{1: 0.9, 2: 0.9}); got != "spam" {
+			t.Errorf("PredictLabel = %q, want spam", got)
+		}
+		if got, _ := m.PredictProbabilityLabel(map[int]float64{3: 1}); got != "eggs" {
+			t.Errorf("PredictProbabilityLabel = %q, want eggs", got)
+		}
+	}
+
+	param.SvmType = EPSILON_SVR
+	if err := NewModel(param).Train(prob); err == nil {
+		t.Error("string labels were accepted for regression")
+	}
+}
+
+func TestNonIntegerLabels(t *testing.T) {
+	prob := newTestProblem([]float64{0.5, 0.7, 0.5, 0.7}, [][]snode{
+		{{index: 1, value: 1}},
+		{{index: 1, value: -1}},
+		{{index: 1, value: 0.9}},
+		{{index: 1, value: -0.9}},
+	})
+
+	param := NewParameter()
+	param.Gamma = 1
+	param.QuietMode = true
+
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(model.Labels(), []float64{0.5, 0.7}) {
+		t.Fatalf("Labels() = %v, want [0.5 0.7]", model.Labels())
+	}
+	if got := model.Predict(map[int]float64{1: -0.95}); got != 0.7 {
+		t.Errorf("Predict = %v, want 0.7", got)
+	}
+	if got := model.PredictLabel(map[int]float64{1: 0.95}); got != "0.5" {
+		t.Errorf("PredictLabel = %q, want 0.5", got)
+	}
+}
+
+func TestSetLabelEncoder(t *testing.T) {
+	train, err := ReadProblem(strings.NewReader("spam 1:1\nham 1:-1\nspam 1:0.9\nham 1:-0.9\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	param := NewParameter()
+	param.Gamma = 0.5
+	param.QuietMode = true
+	model := NewModel(param)
+	if err := model.Train(train); err != nil {
+		t.Fatal(err)
+	}
+
+	// the test file lists the labels in the other order and has a label the model does not know
+	input := "ham 1:-0.8\nspam 1:0.8\nham 1:-1\neggs 1:0.7\n"
+	test, err := ReadProblem(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test.y[0] == train.y[1] {
+		t.Fatalf("test codes %v already match the training codes %v", test.y, train.y)
+	}
+
+	test, err = ReadProblemWithLabels(strings.NewReader(input), model.LabelEncoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(test.y, []float64{1, 0, 1, 2}) {
+		t.Errorf("encoded test labels = %v, want [1 0 1 2]", test.y)
+	}
+	if names := model.LabelEncoder().Names(); !reflect.DeepEqual(names, []string{"spam", "ham"}) {
+		t.Errorf("model labels were modified: %v", names)
+	}
+
+	predicted, _ := model.PredictBatch(test)
+	m, err := EvaluateClassification(test, predicted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Correct != 3 || !reflect.DeepEqual(m.Names, []string{"spam", "ham", "eggs"}) {
+		t.Errorf("correct %d of %d, classes %v", m.Correct, m.Total, m.Names)
+	}
+
+	// numeric labels can be given the codes of a model trained on numeric looking strings
+	numeric, _ := ReadProblem(strings.NewReader("2 1:1\n1 1:-1\n"))
+	if err := numeric.SetLabelEncoder(NewLabelEncoder()); err != nil || !reflect.DeepEqual(numeric.y, []float64{0, 1}) {
+		t.Errorf("numeric labels encoded as %v, %v", numeric.y, err)
+	}
+	if err := test.SetLabelEncoder(nil); err == nil {
+		t.Error("string labels were accepted for a model with numeric labels")
+	}
+}
@@ -0,0 +1,237 @@
+package libSvm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+/**
+ * ClassificationMetrics summarizes how well predicted class labels match the true ones.
+ * Per-class values are in the order of Labels; a ratio with a zero denominator is 0.
+ */
+type ClassificationMetrics struct {
+	Labels    []float64 // the classes found in the true or predicted labels, in ascending order
+	Names     []string  // the original name of each class (see LabelEncoder)
+	Confusion [][]int   // Confusion[i][j] is the number of instances of class i predicted as class j
+	Total     int       // number of instances
+	Correct   int       // number of correctly predicted instances
+	Accuracy  float64
+
+	Precision []float64
+	Recall    []float64
+	F1        []float64
+	Support   []int // number of instances of each class
+
+	MacroPrecision float64 // unweighted mean over the classes
+	MacroRecall    float64
+	MacroF1        float64
+	MicroPrecision float64 // computed from the total true/false positives; equal to Accuracy for single-label data
+	MicroRecall    float64
+	MicroF1        float64
+}
+
+/**
+ * RegressionMetrics summarizes how well predicted function values match the true ones.
+ */
+type RegressionMetrics struct {
+	Total              int     // number of instances
+	MSE                float64 // mean squared error
+	MAE                float64 // mean absolute error
+	R2                 float64 // coefficient of determination, 1 - SS_res/SS_tot
+	SquaredCorrelation float64 // squared correlation coefficient, as reported by svm-train -v and svm-predict
+}
+
+/**
+ * Computes the classification metrics of the predictions yPred for the true labels yTrue.
+ */
+func NewClassificationMetrics(yTrue, yPred []float64) (*ClassificationMetrics, error) {
+	if err := checkPredictions(yTrue, yPred); err != nil {
+		return nil, err
+	}
+
+	m := &ClassificationMetrics{Total: len(yTrue)}
+
+	classOf := make(map[float64]int)
+	for _, ys := range [][]float64{yTrue, yPred} {
+		for _, y := range ys {
+			if _, ok := classOf[y]; !ok {
+				classOf[y] = 0
+				m.Labels = append(m.Labels, y)
+			}
+		}
+	}
+	sort.Float64s(m.Labels)
+
+	var nrClass int = len(m.Labels)
+	m.Names = make([]string, nrClass)
+	for i, y := range m.Labels {
+		classOf[y] = i
+		m.Names[i] = formatLabel(y)
+	}
+
+	m.Confusion = make([][]int, nrClass)
+	for i := range m.Confusion {
+		m.Confusion[i] = make([]int, nrClass)
+	}
+	for k := range yTrue {
+		m.Confusion[classOf[yTrue[k]]][classOf[yPred[k]]]++
+	}
+
+	m.Precision = make([]float64, nrClass)
+	m.Recall = make([]float64, nrClass)
+	m.F1 = make([]float64, nrClass)
+	m.Support = make([]int, nrClass)
+
+	var truePositives, falsePositives, falseNegatives int
+	for i := 0; i < nrClass; i++ {
+		var tp int = m.Confusion[i][i]
+		var predicted, actual int = 0, 0
+		for j := 0; j < nrClass; j++ {
+			predicted += m.Confusion[j][i]
+			actual += m.Confusion[i][j]
+		}
+
+		m.Support[i] = actual
+		m.Precision[i] = ratio(float64(tp), float64(predicted))
+		m.Recall[i] = ratio(float64(tp), float64(actual))
+		m.F1[i] = fScore(m.Precision[i], m.Recall[i])
+
+		m.MacroPrecision += m.Precision[i] / float64(nrClass)
+		m.MacroRecall += m.Recall[i] / float64(nrClass)
+		m.MacroF1 += m.F1[i] / float64(nrClass)
+
+		m.Correct += tp
+		truePositives += tp
+		falsePositives += predicted - tp
+		falseNegatives += actual - tp
+	}
+
+	m.Accuracy = float64(m.Correct) / float64(m.Total)
+	m.MicroPrecision = ratio(float64(truePositives), float64(truePositives+falsePositives))
+	m.MicroRecall = ratio(float64(truePositives), float64(truePositives+falseNegatives))
+	m.MicroF1 = fScore(m.MicroPrecision, m.MicroRecall)
+
+	return m, nil
+}
+
+/**
+ * Computes the regression metrics of the predictions yPred for the true values yTrue.
+ */
+func NewRegressionMetrics(yTrue, yPred []float64) (*RegressionMetrics, error) {
+	if err := checkPredictions(yTrue, yPred); err != nil {
+		return nil, err
+	}
+
+	m := &RegressionMetrics{Total: len(yTrue)}
+
+	var totalError, absError, sumP, sumT, sumPP, sumTT, sumPT float64
+	for k := range yTrue {
+		p, t := yPred[k], yTrue[k]
+		totalError += (p - t) * (p - t)
+		absError += math.Abs(p - t)
+		sumP += p
+		sumT += t
+		sumPP += p * p
+		sumTT += t * t
+		sumPT += p * t
+	}
+
+	n := float64(m.Total)
+	m.MSE = totalError / n
+	m.MAE = absError / n
+	m.SquaredCorrelation = ((n*sumPT - sumP*sumT) * (n*sumPT - sumP*sumT)) /
+		((n*sumPP - sumP*sumP) * (n*sumTT - sumT*sumT))
+
+	mean := sumT / n
+	var totalSquares float64 = 0
+	for _, t := range yTrue {
+		totalSquares += (t - mean) * (t - mean)
+	}
+	if totalSquares > 0 {
+		m.R2 = 1 - totalError/totalSquares
+	} else if totalError == 0 {
+		m.R2 = 1 // constant target predicted exactly
+	}
+
+	return m, nil
+}
+
+/**
+ * Computes the classification metrics of the predictions target for the labels of prob, e.g. of the
+ * result of CrossValidation. Class names are the original string labels if prob has a LabelEncoder.
+ * To evaluate a model's predictions on a separately read problem, first give the problem the codes of
+ * the model with prob.SetLabelEncoder(model.LabelEncoder()).
+ */
+func EvaluateClassification(prob *Problem, target []float64) (*ClassificationMetrics, error) {
+	m, err := NewClassificationMetrics(prob.y[:prob.l], target)
+	if err != nil {
+		return nil, err
+	}
+	for i, y := range m.Labels {
+		m.Names[i] = prob.labelName(y)
+	}
+	return m, nil
+}
+
+/**
+ * Computes the regression metrics of the predictions target for the labels of prob, e.g. of the
+ * result of CrossValidation.
+ */
+func EvaluateRegression(prob *Problem, target []float64) (*RegressionMetrics, error) {
+	return NewRegressionMetrics(prob.y[:prob.l], target)
+}
+
+/**
+ * Returns a table of the per-class precision, recall, F1 and support followed by the averages and the
+ * confusion matrix.
+ */
+func (m *ClassificationMetrics) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%-12s %9s %9s %9s %9s\n", "class", "precision", "recall", "f1", "support")
+	for i := range m.Labels {
+		fmt.Fprintf(&b, "%-12s %9.4f %9.4f %9.4f %9d\n", m.Names[i], m.Precision[i], m.Recall[i], m.F1[i], m.Support[i])
+	}
+	fmt.Fprintf(&b, "%-12s %9.4f %9.4f %9.4f %9d\n", "macro avg", m.MacroPrecision, m.MacroRecall, m.MacroF1, m.Total)
+	fmt.Fprintf(&b, "%-12s %9.4f %9.4f %9.4f %9d\n", "micro avg", m.MicroPrecision, m.MicroRecall, m.MicroF1, m.Total)
+	fmt.Fprintf(&b, "accuracy = %g%% (%d/%d)\n", 100*m.Accuracy, m.Correct, m.Total)
+
+	b.WriteString("confusion matrix (rows: true class, columns: predicted class)\n")
+	for i := range m.Labels {
+		fmt.Fprintf(&b, "%-12s", m.Names[i])
+		for j := range m.Labels {
+			fmt.Fprintf(&b, " %6d", m.Confusion[i][j])
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func (m *RegressionMetrics) String() string {
+	return fmt.Sprintf("MSE = %g, MAE = %g, R2 = %g, squared correlation coefficient = %g\n",
+		m.MSE, m.MAE, m.R2, m.SquaredCorrelation)
+}
+
+func checkPredictions(yTrue, yPred []float64) error {
+	if len(yTrue) != len(yPred) {
+		return fmt.Errorf("Number of predictions %d does not match number of labels %d\n", len(yPred), len(yTrue))
+	}
+	if len(yTrue) == 0 {
+		return fmt.Errorf("No predictions to evaluate\n")
+	}
+	return nil
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+func fScore(precision, recall float64) float64 {
+	return ratio(2*precision*recall, precision+recall)
+}
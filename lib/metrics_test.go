@@ -0,0 +1,119 @@
+package libSvm
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassificationMetrics(t *testing.T) {
+	yTrue := []float64{1, 1, 1, 2, 2, 3}
+	yPred := []float64{1, 1, 2, 2, 3, 3}
+
+	m, err := NewClassificationMetrics(yTrue, yPred)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(m.Labels, []float64{1, 2, 3}) {
+		t.Errorf("labels = %v", m.Labels)
+	}
+	wantConfusion := [][]int{{2, 1, 0}, {0, 1, 1}, {0, 0, 1}}
+	if !reflect.DeepEqual(m.Confusion, wantConfusion) {
+		t.Errorf("confusion = %v, want %v", m.Confusion, wantConfusion)
+	}
+	if m.Correct != 4 || m.Total != 6 || m.Accuracy != 4.0/6 {
+		t.Errorf("accuracy = %g (%d/%d)", m.Accuracy, m.Correct, m.Total)
+	}
+
+	wantPrecision := []float64{1, 0.5, 0.5}
+	wantRecall := []float64{2.0 / 3, 0.5, 1}
+	for i := range wantPrecision {
+		if math.Abs(m.Precision[i]-wantPrecision[i]) > 1e-12 || math.Abs(m.Recall[i]-wantRecall[i]) > 1e-12 {
+			t.Errorf("class %g: precision %g recall %g, want %g %g",
+				m.Labels[i], m.Precision[i], m.Recall[i], wantPrecision[i], wantRecall[i])
+		}
+		wantF1 := 2 * wantPrecision[i] * wantRecall[i] / (wantPrecision[i] + wantRecall[i])
+		if math.Abs(m.F1[i]-wantF1) > 1e-12 {
+			t.Errorf("class %g: f1 %g, want %g", m.Labels[i], m.F1[i], wantF1)
+		}
+	}
+	if math.Abs(m.MacroPrecision-2.0/3) > 1e-12 || math.Abs(m.MacroRecall-13.0/18) > 1e-12 {
+		t.Errorf("macro precision %g recall %g", m.MacroPrecision, m.MacroRecall)
+	}
+	if m.MicroPrecision != m.Accuracy || m.MicroRecall != m.Accuracy || math.Abs(m.MicroF1-m.Accuracy) > 1e-12 {
+		t.Errorf("micro averages %g %g %g, want %g", m.MicroPrecision, m.MicroRecall, m.MicroF1, m.Accuracy)
+	}
+
+	// a class that is predicted but never occurs has precision 0 and no support
+	m, err = NewClassificationMetrics([]float64{1, 1}, []float64{1, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Support, []int{2, 0}) || m.Precision[1] != 0 || m.Recall[1] != 0 || m.F1[1] != 0 {
+		t.Errorf("support %v precision %v recall %v f1 %v", m.Support, m.Precision, m.Recall, m.F1)
+	}
+
+	if _, err := NewClassificationMetrics([]float64{1}, []float64{1, 2}); err == nil {
+		t.Error("expected an error for mismatched lengths")
+	}
+	if _, err := NewClassificationMetrics(nil, nil); err == nil {
+		t.Error("expected an error for no predictions")
+	}
+}
+
+func TestRegressionMetrics(t *testing.T) {
+	m, err := NewRegressionMetrics([]float64{1, 2, 3, 4}, []float64{1.5, 2, 2, 4.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(m.MSE-0.375) > 1e-12 || math.Abs(m.MAE-0.5) > 1e-12 {
+		t.Errorf("MSE %g MAE %g, want 0.375 0.5", m.MSE, m.MAE)
+	}
+	if math.Abs(m.R2-(1-1.5/5)) > 1e-12 {
+		t.Errorf("R2 = %g, want %g", m.R2, 1-1.5/5)
+	}
+	if math.Abs(m.SquaredCorrelation-4.5*4.5/(5*5.5)) > 1e-12 {
+		t.Errorf("squared correlation = %g, want %g", m.SquaredCorrelation, 4.5*4.5/(5*5.5))
+	}
+
+	m, _ = NewRegressionMetrics([]float64{2, 2}, []float64{2, 2})
+	if m.R2 != 1 || m.MSE != 0 {
+		t.Errorf("constant target: R2 %g MSE %g", m.R2, m.MSE)
+	}
+}
+
+func TestEvaluateCrossValidation(t *testing.T) {
+	input := "spam 1:1 2:0.9\n" +
+		"ham 1:-1 2:-0.8\n" +
+		"spam 1:0.8 2:1\n" +
+		"ham 1:-0.9 2:-1\n" +
+		"spam 1:0.9 2:0.8\n" +
+		"ham 1:-0.8 2:-0.9\n"
+	prob, err := ReadProblem(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param := NewParameter()
+	param.Gamma = 0.5
+	param.QuietMode = true
+
+	target := CrossValidation(prob, param, 3)
+	m, err := EvaluateClassification(prob, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Names, []string{"spam", "ham"}) {
+		t.Errorf("names = %v, want spam, ham", m.Names)
+	}
+	if m.Accuracy != 1 {
+		t.Errorf("accuracy = %g, want 1\n%s", m.Accuracy, m)
+	}
+
+	if _, err := EvaluateRegression(prob, target[:2]); err == nil {
+		t.Error("expected an error for a short target")
+	}
+}
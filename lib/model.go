@@ -1,16 +1,21 @@
-package main
+package libSvm
 
 import (
-	"fmt"
+	"context"
 	"math"
-	"os"
+	"math/rand"
 )
 
+/**
+ * Model is a trained support vector machine. It is produced by Train or
+ * restored from a LIBSVM model file with ReadModel.
+ */
 type Model struct {
 	param     *Parameter
 	l         int
 	nrClass   int
-	label     []int
+	label     []float64
+	labels    *LabelEncoder // string labels; nil if the labels are numeric
 	rho       []float64
 	nSV       []int
 	sV        []int
@@ -21,15 +26,15 @@ type Model struct {
 	probB     []float64
 }
 
-func groupClasses(prob *Problem) (nrClass int, label []int, start []int, count []int, perm []int) {
+func groupClasses(prob *Problem) (nrClass int, label []float64, start []int, count []int, perm []int) {
 	var l int = prob.l
 
-	label = make([]int, 0)
+	label = make([]float64, 0)
 	count = make([]int, 0)
 	data_label := make([]int, l)
 
 	for i := 0; i < l; i++ { // find unqie labels and put them in the label slice
-		this_label := int(prob.y[i])
+		this_label := prob.y[i]
 		var j int
 		for j = 0; j < len(label); j++ {
 			if this_label == label[j] {
@@ -82,7 +87,7 @@ func groupClasses(prob *Problem) (nrClass int, label []int, start []int, count [
 	return // nrClass, label, start, count, perm
 }
 
-func (model *Model) classification(prob *Problem) {
+func (model *Model) classification(ctx context.Context, prob *Problem, rng *rand.Rand) error {
 
 	nrClass, label, start, count, perm := groupClasses(prob) // group SV with the same labels together
 
@@ -95,16 +100,19 @@ func (model *Model) classification(prob *Problem) {
 	weighted_C := make([]float64, nrClass)
 	for i := 0; i < nrClass; i++ {
 		weighted_C[i] = model.param.C
+		if model.param.Balanced { // computed on the training data, i.e. per fold in cross validation
+			weighted_C[i] *= float64(l) / (float64(nrClass) * float64(count[i]))
+		}
 	}
 	for i := 0; i < model.param.NrWeight; i++ { // this is only done if the relative weight of the labels have been set by the user
 		var j int = 0
 		for j = 0; j < nrClass; j++ {
-			if model.param.WeightLabel[i] == label[j] {
+			if float64(model.param.WeightLabel[i]) == label[j] {
 				break
 			}
 		}
 		if j == nrClass {
-			fmt.Fprintf(os.Stderr, "WARNING: class label %d specified in weight is not found\n", model.param.WeightLabel[i])
+			model.param.info("WARNING: class label %d specified in weight is not found\n", model.param.WeightLabel[i])
 		} else {
 			weighted_C[j] = weighted_C[j] * model.param.Weight[i] // multiple with user specified weight for label
 		}
@@ -123,6 +131,8 @@ func (model *Model) classification(prob *Problem) {
 		probB = make([]float64, totalCompares)
 	}
 
+	var maxIterReached bool = false
+	var stopped bool = false
 	var p int = 0
 	for i := 0; i < nrClass; i++ {
 		for j := i + 1; j < nrClass; j++ {
@@ -148,13 +158,28 @@ func (model *Model) classification(prob *Problem) {
 				subProb.y[ci+k] = -1
 			}
 
+			if prob.w != nil {
+				subProb.w = make([]float64, subProb.l)
+				for k := 0; k < ci; k++ {
+					subProb.w[k] = prob.w[perm[si+k]]
+				}
+				for k := 0; k < cj; k++ {
+					subProb.w[ci+k] = prob.w[perm[sj+k]]
+				}
+			}
+
 			if model.param.Probability {
-				probA[p], probB[p] = binarySvcProbability(&subProb, model.param, weighted_C[i], weighted_C[j])
+				var err error
+				if probA[p], probB[p], err = binarySvcProbability(ctx, &subProb, model.param, weighted_C[i], weighted_C[j], rng); err != nil {
+					return err
+				}
 			}
 
-			if decision_result, err := train_one(&subProb, model.param, weighted_C[i], weighted_C[j]); err == nil { // no error in training
+			if decision_result, err := train_one(ctx, &subProb, model.param, weighted_C[i], weighted_C[j]); err == nil { // no error in training
 
 				decisions[p] = decision_result
+				maxIterReached = maxIterReached || decision_result.maxIterReached
+				stopped = stopped || decision_result.stopped
 
 				for k := 0; k < ci; k++ {
 					if !nonzero[si+k] && math.Abs(decisions[p].alpha[k]) > 0 {
@@ -168,8 +193,7 @@ func (model *Model) classification(prob *Problem) {
 				}
 
 			} else {
-				fmt.Println("WARNING: training failed: ", err)
-				return // no point in continuing
+				return err // no point in continuing
 			}
 
 			p++
@@ -178,10 +202,11 @@ func (model *Model) classification(prob *Problem) {
 
 	// Update the model!
 	model.nrClass = nrClass
-	model.label = make([]int, nrClass)
+	model.label = make([]float64, nrClass)
 	for i := 0; i < nrClass; i++ {
 		model.label[i] = label[i]
 	}
+	model.labels = prob.labels
 
 	model.rho = make([]float64, len(decisions))
 	for i := 0; i < len(decisions); i++ {
@@ -208,7 +233,7 @@ func (model *Model) classification(prob *Problem) {
 		nz_count[i] = nSV
 	}
 
-	fmt.Printf("Total nSV = %d\n", totalSV)
+	model.param.info("Total nSV = %d\n", totalSV)
 
 	model.l = totalSV
 	model.svSpace = prob.xSpace
@@ -268,59 +293,209 @@ func (model *Model) classification(prob *Problem) {
 		}
 	}
 
+	return model.checkStopped(maxIterReached, stopped)
 }
 
-func (model *Model) regressionOneClass(prob *Problem) {
+/**
+ * Reports a partial solution if Parameter.Progress or the iteration limit (Parameter.MaxIter, or the default
+ * limit if it is 0) stopped the solver before it converged
+ */
+func (model *Model) checkStopped(maxIterReached, stopped bool) error {
+	if stopped {
+		return &TrainStoppedError{Reason: STOPPED, Partial: true}
+	}
+	if maxIterReached {
+		return &TrainStoppedError{Reason: MAX_ITER_REACHED, Partial: true}
+	}
+	return nil
+}
+
+func (model *Model) regressionOneClass(ctx context.Context, prob *Problem, rng *rand.Rand) error {
 
 	model.nrClass = 2
 
 	if model.param.Probability &&
 		(model.param.SvmType == EPSILON_SVR || model.param.SvmType == NU_SVR) {
 		model.probA = make([]float64, 1)
-		model.probA[0] = svrProbability(prob, model.param)
+		var err error
+		if model.probA[0], err = svrProbability(ctx, prob, model.param, rng); err != nil {
+			return err
+		}
 	}
 
-	if decision_result, err := train_one(prob, model.param, 0, 0); err == nil { // no error in training
-		model.rho = append(model.rho, decision_result.rho)
+	decision_result, err := train_one(ctx, prob, model.param, 0, 0)
+	if err != nil {
+		return err
+	}
 
-		var nSV int = 0
-		for i := 0; i < prob.l; i++ {
-			if math.Abs(decision_result.alpha[i]) > 0 {
-				nSV++
-			}
-		}
+	model.rho = append(model.rho, decision_result.rho)
 
-		model.l = nSV
-		model.svSpace = prob.xSpace
-		model.sV = make([]int, nSV)
-		model.svCoef = make([][]float64, 1)
-		model.svCoef[0] = make([]float64, nSV)
-		model.svIndices = make([]int, nSV)
+	var nSV int = 0
+	for i := 0; i < prob.l; i++ {
+		if math.Abs(decision_result.alpha[i]) > 0 {
+			nSV++
+		}
+	}
 
-		var j int = 0
-		for i := 0; i < prob.l; i++ {
-			if math.Abs(decision_result.alpha[i]) > 0 {
-				model.sV[j] = prob.x[i]
-				model.svCoef[0][j] = decision_result.alpha[i]
-				model.svIndices[j] = i + 1
-				j++
-			}
+	model.l = nSV
+	model.svSpace = prob.xSpace
+	model.sV = make([]int, nSV)
+	model.svCoef = make([][]float64, 1)
+	model.svCoef[0] = make([]float64, nSV)
+	model.svIndices = make([]int, nSV)
+
+	var j int = 0
+	for i := 0; i < prob.l; i++ {
+		if math.Abs(decision_result.alpha[i]) > 0 {
+			model.sV[j] = prob.x[i]
+			model.svCoef[0][j] = decision_result.alpha[i]
+			model.svIndices[j] = i + 1
+			j++
 		}
-	} else {
-		fmt.Println("WARNING: training failed: ", err)
 	}
+
+	return model.checkStopped(decision_result.maxIterReached, decision_result.stopped)
 }
 
+/**
+ * Trains the model on the given problem using the parameters the model was created with.
+ * The parameters are checked with Parameter.Validate first.
+ */
 func (model *Model) Train(prob *Problem) error {
+	return model.TrainContext(context.Background(), prob)
+}
+
+/**
+ * Same as Train, but stops when ctx is cancelled or times out. In that case the model is discarded and
+ * a *TrainStoppedError wrapping ctx.Err() is returned. If the solver needs more iterations than
+ * Parameter.MaxIter (or the default limit if it is 0), or Parameter.Progress asks the solver to stop, training
+ * completes with the partial solution and a *TrainStoppedError with Partial set is returned.
+ */
+func (model *Model) TrainContext(ctx context.Context, prob *Problem) error {
+	return model.train(ctx, prob, model.param.newRand())
+}
+
+/**
+ * Same as TrainContext, drawing the random numbers of the probability estimates from rng
+ */
+func (model *Model) train(ctx context.Context, prob *Problem, rng *rand.Rand) error {
+	if err := model.param.Validate(prob); err != nil {
+		return err
+	}
+
+	*model = Model{param: model.param} // forget a previous training of the model
+	trainProb, kept := prob.removeZeroWeight()
+
+	var err error = ctx.Err() // do not start if ctx is already done
+	if err == nil {
+		switch model.param.SvmType {
+		case C_SVC, NU_SVC:
+			err = model.classification(ctx, trainProb, rng)
+		case ONE_CLASS, EPSILON_SVR, NU_SVR:
+			err = model.regressionOneClass(ctx, trainProb, rng)
+		default:
+			err = &trainError{val: model.param.SvmType, msg: "svm type not supported"}
+		}
+	}
+
+	if err != nil && ctx.Err() != nil {
+		*model = Model{param: model.param} // discard whatever was trained so far
+		return &TrainStoppedError{Reason: CANCELLED, Partial: false, Err: ctx.Err()}
+	}
+	if !isUsableModel(err) {
+		return err // the model was not trained
+	}
+
+	if kept != nil { // SV indices refer to prob, not to the reduced problem
+		for k := range model.svIndices {
+			model.svIndices[k] = kept[model.svIndices[k]-1] + 1
+		}
+	}
+	return err
+}
+
+/**
+ * Returns an untrained model that will use the given parameters. Use Train to fit it to a problem,
+ * or ReadModel to restore a previously saved model (in which case param may be nil).
+ */
+func NewModel(param *Parameter) *Model {
+	if param == nil {
+		param = NewParameter()
+	}
+	return &Model{param: param}
+}
+
+/**
+ * Returns the svm type of the model (C_SVC, NU_SVC, ONE_CLASS, EPSILON_SVR or NU_SVR).
+ */
+func (model *Model) SvmType() int {
+	return model.param.SvmType
+}
+
+/**
+ * Returns the number of classes. For regression and one-class models this is 2.
+ */
+func (model *Model) NrClass() int {
+	return model.nrClass
+}
+
+/**
+ * Returns the class labels in the order used by PredictValues and PredictProbability.
+ * Returns nil for regression and one-class models. For string labels these are the encoded labels.
+ */
+func (model *Model) Labels() []float64 {
+	return model.label
+}
+
+/**
+ * Returns the original class labels in the order of Labels: the string labels if the model was trained
+ * on them, the formatted numeric labels otherwise.
+ */
+func (model *Model) LabelNames() []string {
+	if model.label == nil {
+		return nil
+	}
+	names := make([]string, len(model.label))
+	for i, y := range model.label {
+		names[i] = model.labelName(y)
+	}
+	return names
+}
+
+/**
+ * Returns the encoder of the string labels the model was trained on, or nil if the labels are numeric.
+ */
+func (model *Model) LabelEncoder() *LabelEncoder {
+	return model.labels
+}
+
+func (model *Model) labelName(y float64) string {
+	if model.labels != nil {
+		return model.labels.Decode(y)
+	}
+	return formatLabel(y)
+}
+
+/**
+ * Returns true if the model contains the information needed for probability estimates.
+ */
+func (model *Model) CheckProbabilityModel() bool {
 	switch model.param.SvmType {
 	case C_SVC, NU_SVC:
-		model.classification(prob)
-	case ONE_CLASS, EPSILON_SVR, NU_SVR:
-		model.regressionOneClass(prob)
+		return model.probA != nil && model.probB != nil
+	case EPSILON_SVR, NU_SVR:
+		return model.probA != nil
 	}
-	return nil
+	return false
 }
 
-func NewModel(param *Parameter) Model {
-	return Model{param: param}
+/**
+ * Returns the sigma of the Laplace distribution used for probability estimates of a regression model,
+ * or 0 if the model has no probability information.
+ */
+func (model *Model) SvrProbability() float64 {
+	if (model.param.SvmType == EPSILON_SVR || model.param.SvmType == NU_SVR) && model.probA != nil {
+		return model.probA[0]
+	}
+	return 0
 }
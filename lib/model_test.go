@@ -0,0 +1,335 @@
+package libSvm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTrainContextCancelled(t *testing.T) {
+	prob := newRandomProblem(200, 3, false, 1)
+
+	param := NewParameter()
+	param.QuietMode = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	model := NewModel(param)
+	err := model.TrainContext(ctx, prob)
+
+	var stopped *TrainStoppedError
+	if !errors.As(err, &stopped) {
+		t.Fatalf("TrainContext error = %v, want *TrainStoppedError", err)
+	}
+	if stopped.Reason != CANCELLED || stopped.Partial {
+		t.Errorf("reason = %d, partial = %v, want CANCELLED and discarded", stopped.Reason, stopped.Partial)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v does not wrap context.Canceled", err)
+	}
+	if model.l != 0 || model.NrClass() != 0 {
+		t.Errorf("cancelled model was not discarded: l = %d, nrClass = %d", model.l, model.NrClass())
+	}
+}
+
+func TestTrainReusedModel(t *testing.T) {
+	prob := newRandomProblem(200, 3, true, 1)
+	zeroed := newRandomProblem(200, 3, true, 1)
+	w := make([]float64, zeroed.l)
+	for i := range w {
+		w[i] = float64(i % 2) // half the instances are left out
+	}
+	if err := zeroed.SetWeights(w); err != nil {
+		t.Fatal(err)
+	}
+
+	param := NewParameter()
+	param.SvmType = EPSILON_SVR
+	param.QuietMode = true
+
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+
+	// the SV indices of the previous training must not be mapped through the instances kept from zeroed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var stopped *TrainStoppedError
+	if err := model.TrainContext(ctx, zeroed); !errors.As(err, &stopped) || stopped.Reason != CANCELLED {
+		t.Fatalf("TrainContext error = %v, want CANCELLED", err)
+	}
+
+	for k := 0; k < 2; k++ {
+		if err := model.Train(zeroed); err != nil {
+			t.Fatal(err)
+		}
+		if len(model.rho) != 1 {
+			t.Fatalf("training %d: rho = %v, want one value", k, model.rho)
+		}
+		for _, index := range model.svIndices {
+			if w[index-1] == 0 {
+				t.Errorf("training %d: instance %d of weight 0 is a support vector", k, index)
+			}
+		}
+	}
+}
+
+func TestTrainMaxIter(t *testing.T) {
+	prob := newRandomProblem(200, 3, false, 1)
+
+	param := NewParameter()
+	param.C = 10
+	param.MaxIter = 5
+	param.QuietMode = true
+
+	model := NewModel(param)
+	err := model.Train(prob)
+
+	var stopped *TrainStoppedError
+	if !errors.As(err, &stopped) {
+		t.Fatalf("Train error = %v, want *TrainStoppedError", err)
+	}
+	if stopped.Reason != MAX_ITER_REACHED || !stopped.Partial {
+		t.Errorf("reason = %d, partial = %v, want MAX_ITER_REACHED and partial", stopped.Reason, stopped.Partial)
+	}
+	if model.NrClass() != 2 {
+		t.Fatalf("partial model has %d classes, want 2", model.NrClass())
+	}
+	model.Predict(map[int]float64{1: 0.5, 2: -0.5, 3: 1}) // the partial model must be usable
+
+	param.MaxIter = -1
+	if err := NewModel(param).Train(prob); err == nil {
+		t.Error("negative max_iter was accepted")
+	}
+
+	// the default limit (MaxIter = 0) is reported the same way
+	param.MaxIter = 0
+	err = NewModel(param).checkStopped(true, false)
+	if !errors.As(err, &stopped) || stopped.Reason != MAX_ITER_REACHED || !stopped.Partial {
+		t.Errorf("default limit: error = %v, want a partial MAX_ITER_REACHED", err)
+	}
+}
+
+func TestTrainInstanceWeights(t *testing.T) {
+	base := newRandomProblem(60, 3, false, 2)
+
+	// weight 2 on the first 10 instances is the same as having them twice
+	weighted := newRandomProblem(60, 3, false, 2)
+	w := make([]float64, weighted.l)
+	for i := range w {
+		w[i] = 1
+		if i < 10 {
+			w[i] = 2
+		}
+	}
+	if err := weighted.SetWeights(w); err != nil {
+		t.Fatal(err)
+	}
+
+	duplicated := newRandomProblem(60, 3, false, 2)
+	for i := 0; i < 10; i++ {
+		duplicated.x = append(duplicated.x, base.x[i])
+		duplicated.y = append(duplicated.y, base.y[i])
+		duplicated.l++
+	}
+
+	// weight 0 is the same as leaving the instances out
+	zeroed := newRandomProblem(60, 3, false, 2)
+	for i := range w {
+		w[i] = 1
+		if i%3 == 0 {
+			w[i] = 0
+		}
+	}
+	if err := zeroed.SetWeights(w); err != nil {
+		t.Fatal(err)
+	}
+	removed := &Problem{xSpace: base.xSpace}
+	for i := 0; i < base.l; i++ {
+		if i%3 != 0 {
+			removed.x = append(removed.x, base.x[i])
+			removed.y = append(removed.y, base.y[i])
+			removed.l++
+		}
+	}
+
+	for _, svmType := range []int{C_SVC, NU_SVC, EPSILON_SVR} {
+		param := NewParameter()
+		param.SvmType = svmType
+		param.C = 5
+		param.Nu = 0.3
+		param.Gamma = 0.5
+		param.Eps = 1e-6
+		param.QuietMode = true
+
+		train := func(prob *Problem) *Model {
+			model := NewModel(param)
+			if err := model.Train(prob); err != nil {
+				t.Fatal(err)
+			}
+			return model
+		}
+
+		for _, pair := range [][2]*Problem{{weighted, duplicated}, {zeroed, removed}} {
+			got, want := train(pair[0]), train(pair[1])
+			if math.Abs(got.rho[0]-want.rho[0]) > 1e-4 {
+				t.Errorf("%s: rho = %v, want %v", svm_type_string[svmType], got.rho[0], want.rho[0])
+			}
+		}
+
+		model := train(zeroed)
+		for _, index := range model.svIndices {
+			if w[index-1] == 0 {
+				t.Errorf("%s: instance %d of weight 0 is a support vector", svm_type_string[svmType], index)
+			}
+		}
+	}
+}
+
+func TestTrainBalanced(t *testing.T) {
+	rng := newRandomProblem(100, 2, false, 3)
+	prob := &Problem{xSpace: rng.xSpace}
+	for i := 0; i < rng.l; i++ {
+		if rng.y[i] < 0 && i%4 != 0 { // keep about one in four negatives
+			continue
+		}
+		prob.x = append(prob.x, rng.x[i])
+		prob.y = append(prob.y, rng.y[i])
+		prob.l++
+	}
+
+	var nrPos, nrNeg float64
+	for _, y := range prob.y {
+		if y > 0 {
+			nrPos++
+		} else {
+			nrNeg++
+		}
+	}
+
+	param := NewParameter()
+	param.Gamma = 0.5
+	param.QuietMode = true
+	param.Balanced = true
+	balanced := NewModel(param)
+	if err := balanced.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+
+	manualParam := NewParameter()
+	manualParam.Gamma = 0.5
+	manualParam.QuietMode = true
+	manualParam.NrWeight = 2
+	manualParam.WeightLabel = []int{1, -1}
+	manualParam.Weight = []float64{float64(prob.l) / (2 * nrPos), float64(prob.l) / (2 * nrNeg)}
+	manual := NewModel(manualParam)
+	if err := manual.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+
+	if nrPos < 2*nrNeg {
+		t.Fatalf("problem is not imbalanced: %v positives, %v negatives", nrPos, nrNeg)
+	}
+	if balanced.rho[0] != manual.rho[0] {
+		t.Errorf("balanced rho = %v, want %v as with the hand computed weights", balanced.rho[0], manual.rho[0])
+	}
+}
+
+func TestSeedReproducible(t *testing.T) {
+	prob := newRandomProblem(150, 3, false, 6)
+
+	param := NewParameter()
+	param.Gamma = 0.5
+	param.Probability = true
+	param.QuietMode = true
+	param.Seed = 42
+
+	train := func(seed int64) *Model {
+		param.Seed = seed
+		model := NewModel(param)
+		if err := model.Train(prob); err != nil {
+			t.Fatal(err)
+		}
+		return model
+	}
+	m1, m2 := train(42), train(42)
+	if m1.probA[0] != m2.probA[0] || m1.probB[0] != m2.probB[0] {
+		t.Errorf("probA/probB differ with the same seed: %g %g, %g %g", m1.probA[0], m1.probB[0], m2.probA[0], m2.probB[0])
+	}
+	if m3 := train(43); m3.probA[0] == m1.probA[0] && m3.probB[0] == m1.probB[0] {
+		t.Errorf("probA/probB are the same with another seed")
+	}
+
+	// cross validation, including the probability folds of each model, run concurrently
+	param.Seed = 7
+	targets := make([][]float64, 4)
+	done := make(chan bool)
+	for i := range targets {
+		go func(i int) {
+			targets[i] = CrossValidation(prob, param, 5)
+			done <- true
+		}(i)
+	}
+	for range targets {
+		<-done
+	}
+	for i := 1; i < len(targets); i++ {
+		for j := range targets[0] {
+			if targets[i][j] != targets[0][j] {
+				t.Fatalf("run %d: target[%d] = %g, want %g", i, j, targets[i][j], targets[0][j])
+			}
+		}
+	}
+
+	// an explicit source overrides Parameter.Seed
+	param.Seed = 0
+	param.Probability = false
+	regressionProb := newRandomProblem(80, 3, true, 7)
+	param.SvmType = EPSILON_SVR
+	r1, err := CrossValidationRand(context.Background(), regressionProb, param, 5, rand.New(rand.NewSource(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, _ := CrossValidationRand(context.Background(), regressionProb, param, 5, rand.New(rand.NewSource(3)))
+	for j := range r1 {
+		if r1[j] != r2[j] {
+			t.Fatalf("target[%d] = %g and %g with the same source", j, r1[j], r2[j])
+		}
+	}
+}
+
+func TestCrossValidationError(t *testing.T) {
+	// nu = 0.74 is feasible for 3 positive and 5 negative instances, but not for the training
+	// folds with 1 positive and 2 negative instances
+	prob, err := NewProblemFromDense([]float64{1, 1, 1, -1, -1, -1, -1, -1},
+		[][]float64{{1}, {0.9}, {0.8}, {-1}, {-0.9}, {-0.8}, {-0.7}, {-0.6}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param := NewParameter()
+	param.SvmType = NU_SVC
+	param.Nu = 0.74
+	param.Gamma = 1
+	var output string
+	param.Logger = func(msg string) { output += msg }
+
+	if err := param.Validate(prob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CrossValidationContext(context.Background(), prob, param, 2); err == nil {
+		t.Fatal("expected the infeasible nu of a fold to be reported")
+	}
+
+	if target := CrossValidation(prob, param, 2); target != nil {
+		t.Errorf("target = %v, want nil", target)
+	}
+	if !strings.Contains(output, "ERROR: cross validation failed: specified nu") {
+		t.Errorf("output = %q, want the training error", output)
+	}
+}
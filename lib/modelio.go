@@ -1,13 +1,20 @@
-package main
+package libSvm
 
 import (
 	"bufio"
+	"compress/gzip"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 )
 
+/**
+ * Saves the model to the specified file in LIBSVM model format. The model is gzip compressed
+ * if the file name ends in ".gz".
+ */
 func (model *Model) Dump(file string) error {
 	f, err := os.Create(file)
 	if err != nil {
@@ -16,6 +23,24 @@ func (model *Model) Dump(file string) error {
 
 	defer f.Close() // close f on method return
 
+	if !strings.HasSuffix(file, ".gz") {
+		if err := model.write(f); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	zw := gzip.NewWriter(f)
+	if err := model.write(zw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (model *Model) write(w io.Writer) error {
 	var output []string
 
 	//svm_type_string := [5]string{"c_svc", "nu_svc", "one_class", "epsilon_svr", "nu_svr"}
@@ -51,7 +76,15 @@ func (model *Model) Dump(file string) error {
 	if len(model.label) > 0 {
 		output = append(output, "label")
 		for i := 0; i < nrClass; i++ {
-			output = append(output, fmt.Sprintf(" %d", model.label[i]))
+			output = append(output, " "+formatLabel(model.label[i]))
+		}
+		output = append(output, "\n")
+	}
+
+	if model.labels != nil { // original string labels, in the order of the label line
+		output = append(output, "label_names")
+		for i := 0; i < nrClass; i++ {
+			output = append(output, " "+strconv.Quote(model.labels.Decode(model.label[i])))
 		}
 		output = append(output, "\n")
 	}
@@ -74,7 +107,7 @@ func (model *Model) Dump(file string) error {
 
 	if len(model.nSV) > 0 {
 		output = append(output, "nr_sv")
-		for i := 0; i < total_models; i++ {
+		for i := 0; i < nrClass; i++ {
 			output = append(output, fmt.Sprintf(" %d", model.nSV[i]))
 		}
 		output = append(output, "\n")
@@ -89,7 +122,7 @@ func (model *Model) Dump(file string) error {
 
 		i_idx := model.sV[i]
 		if model.param.KernelType == PRECOMPUTED {
-			output = append(output, fmt.Sprintf("0:%d ", model.svSpace[i_idx]))
+			output = append(output, fmt.Sprintf("0:%d \n", int(model.svSpace[i_idx].value)))
 		} else {
 			for model.svSpace[i_idx].index != -1 {
 				index := model.svSpace[i_idx].index
@@ -101,7 +134,9 @@ func (model *Model) Dump(file string) error {
 		}
 	}
 
-	f.WriteString(strings.Join(output, ""))
+	if _, err := io.WriteString(w, strings.Join(output, "")); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -192,13 +227,32 @@ func (model *Model) readHeader(scanner *bufio.Scanner) error {
 				return fmt.Errorf("Number of labels %d does not appear in the file\n", model.nrClass)
 			}
 
-			model.label = make([]int, model.nrClass)
+			model.label = make([]float64, model.nrClass)
 			for i = 0; i < model.nrClass; i++ {
-				if model.label[i], err = strconv.Atoi(tokens[i+1]); err != nil {
+				if model.label[i], err = strconv.ParseFloat(tokens[i+1], 64); err != nil {
 					return err
 				}
 			}
 
+		case "label_names":
+
+			names, err := parseLabelNames(strings.TrimPrefix(line, "label_names"))
+			if err != nil {
+				return err
+			}
+			if len(model.label) != model.nrClass || len(names) != model.nrClass {
+				return fmt.Errorf("Number of label names %d does not match the labels in the file\n", len(names))
+			}
+
+			model.labels = NewLabelEncoder()
+			for i = 0; i < model.nrClass; i++ {
+				code := int(model.label[i])
+				if float64(code) != model.label[i] || code < 0 {
+					return fmt.Errorf("Label %g of a string label is not a valid code\n", model.label[i])
+				}
+				model.labels.set(code, names[i])
+			}
+
 		case "probA":
 
 			total_class_comparisons := model.nrClass * (model.nrClass - 1) / 2
@@ -251,6 +305,10 @@ func (model *Model) readHeader(scanner *bufio.Scanner) error {
 	return fmt.Errorf("Fail to completely read header")
 }
 
+/**
+ * Restores a model from the specified file in LIBSVM model format. gzip and bzip2 compressed
+ * files are decompressed on the fly.
+ */
 func (model *Model) ReadModel(file string) error {
 	f, err := os.Open(file)
 	if err != nil {
@@ -259,9 +317,21 @@ func (model *Model) ReadModel(file string) error {
 
 	defer f.Close() // close f on method return
 
-	scanner := bufio.NewScanner(f)
+	r, err := decompress(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), math.MaxInt32) // SV lines can be very long
+
+	if model.param == nil {
+		model.param = NewParameter()
+	}
 
-	model.readHeader(scanner)
+	if err := model.readHeader(scanner); err != nil {
+		return err
+	}
 
 	var l int = model.l           // read l from header
 	var m int = model.nrClass - 1 // read nrClass from header
@@ -273,14 +343,18 @@ func (model *Model) ReadModel(file string) error {
 	for i := 0; i < l; i++ {
 		model.sV = append(model.sV, len(model.svSpace)) // starting index into svSpace for this SV
 
-		scanner.Scan() // scan a line
+		if !scanner.Scan() { // scan a line
+			return fmt.Errorf("Fail to read SV %d of %d\n", i+1, l)
+		}
 		line := scanner.Text()
 
 		tokens := strings.Fields(line) // get all the word tokens (seperated by white spaces)
 		var k int = 0
 		for _, token := range tokens {
 			if k < m {
-				model.svCoef[k][i], err = strconv.ParseFloat(token, 64)
+				if model.svCoef[k][i], err = strconv.ParseFloat(token, 64); err != nil {
+					return fmt.Errorf("Fail to parse svCoef from token %v\n", token)
+				}
 				k++
 			} else {
 				node := strings.Split(token, ":")
@@ -301,5 +375,5 @@ func (model *Model) ReadModel(file string) error {
 		model.svSpace = append(model.svSpace, snode{index: -1})
 	}
 
-	return nil
+	return scanner.Err()
 }
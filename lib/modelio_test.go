@@ -0,0 +1,120 @@
+package libSvm
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func denseTestProblem(y []float64, X [][]float64) *Problem {
+	prob := &Problem{l: len(y), y: y}
+	for _, row := range X {
+		prob.x = append(prob.x, len(prob.xSpace))
+		for j, v := range row {
+			prob.xSpace = append(prob.xSpace, snode{index: j + 1, value: v})
+		}
+		prob.xSpace = append(prob.xSpace, snode{index: -1})
+	}
+	return prob
+}
+
+/**
+ * Dumps the model and returns the header lines by keyword and the SV lines
+ */
+func dumpLines(t *testing.T, model *Model) (map[string][]string, []string) {
+	file := filepath.Join(t.TempDir(), "model")
+	if err := model.Dump(file); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := make(map[string][]string)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		if line == "SV" {
+			return header, lines[i+1:]
+		}
+		fields := strings.Fields(line)
+		header[fields[0]] = fields[1:]
+	}
+	t.Fatalf("no SV section in %q", data)
+	return nil, nil
+}
+
+func TestDumpNrSV(t *testing.T) {
+	for _, nrClass := range []int{2, 4} {
+		var y []float64
+		var X [][]float64
+		for i := 0; i < 40; i++ {
+			c := i % nrClass
+			y = append(y, float64(c))
+			X = append(X, []float64{float64(c) + float64(i)/100, float64(c * c)})
+		}
+
+		param := NewParameter()
+		param.Gamma = 0.5
+		param.QuietMode = true
+		model := NewModel(param)
+		if err := model.Train(denseTestProblem(y, X)); err != nil {
+			t.Fatal(err)
+		}
+
+		// one count per class, adding up to total_sv
+		header, _ := dumpLines(t, model)
+		if len(header["nr_sv"]) != nrClass {
+			t.Fatalf("%d classes: nr_sv = %v", nrClass, header["nr_sv"])
+		}
+		var sum int = 0
+		for _, field := range header["nr_sv"] {
+			n, _ := strconv.Atoi(field)
+			sum += n
+		}
+		if total := header["total_sv"][0]; strconv.Itoa(sum) != total {
+			t.Errorf("%d classes: nr_sv = %v, total_sv = %s", nrClass, header["nr_sv"], total)
+		}
+	}
+}
+
+func TestDumpPrecomputed(t *testing.T) {
+	// SVs of a precomputed kernel are written as their serial number, one per line
+	var y []float64
+	var X [][]float64
+	for i := 0; i < 10; i++ {
+		y = append(y, float64(1-2*(i%2)))
+		X = append(X, []float64{y[i] * float64(i+1)})
+	}
+	prob := denseTestProblem(y, X)
+
+	param := NewParameter()
+	param.KernelType = LINEAR
+	param.QuietMode = true
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+
+	var svSpace []snode
+	for i := range model.sV {
+		serial := (model.sV[i]-prob.x[0])/2 + 1 // every instance has one feature and the terminator
+		model.sV[i] = len(svSpace)
+		svSpace = append(svSpace, snode{index: 0, value: float64(serial)}, snode{index: -1})
+	}
+	model.svSpace = svSpace
+	param.KernelType = PRECOMPUTED
+
+	_, sv := dumpLines(t, model)
+	if len(sv) != model.l {
+		t.Fatalf("%d SV lines, want %d: %q", len(sv), model.l, sv)
+	}
+	for i, line := range sv {
+		fields := strings.Fields(line)
+		if want := "0:" + strconv.Itoa(int(svSpace[2*i].value)); len(fields) != 2 || fields[1] != want {
+			t.Errorf("SV line %q, want coefficient and %s", line, want)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package libSvm
 
 import (
 	//"fmt"
@@ -57,10 +57,20 @@ func (p parallelRunner) waitAll() {
 }
 
 func NewParallelRunner(n int) parallelRunner {
+	return newParallelRunnerLimit(n, 0)
+}
+
+/**
+ * Returns a runner that uses at most limit CPUs, or all of them if limit is 0
+ */
+func newParallelRunnerLimit(n, limit int) parallelRunner {
 	cpus := runtime.NumCPU() // query the number of available CPUs
 	runtime.GOMAXPROCS(cpus) // set this as the number of usable CPUs
 
 	cpus = runtime.GOMAXPROCS(0) // get the new max number of CPUs
+	if limit > 0 && limit < cpus {
+		cpus = limit
+	}
 
 	p := parallelRunner{N: n, numCPU: cpus}
 
@@ -1,4 +1,11 @@
-package main
+package libSvm
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+)
 
 const (
 	C_SVC       = iota
@@ -19,6 +26,9 @@ const (
 var svm_type_string = []string{"c_svc", "nu_svc", "one_class", "epsilon_svr", "nu_svr"}
 var kernel_type_string = []string{"linear", "polynomial", "rbf", "sigmoid", "precomputed"}
 
+/**
+ * Parameter holds the training options of a model, mirroring LIBSVM's svm_parameter.
+ */
 type Parameter struct {
 	SvmType    int
 	KernelType int
@@ -29,14 +39,169 @@ type Parameter struct {
 	Eps         float64 // stopping criteria
 	C           float64 // penality
 	NrWeight    int
-	WeightLabel []int
+	WeightLabel []int // classes whose C is multiplied by Weight; for string labels, their LabelEncoder codes
 	Weight      []float64
+	Balanced    bool // weight the C of each class by l/(nrClass*count), inversely proportional to class frequency
 	Nu          float64
 	P           float64
 	Probability bool
+	Shrinking   bool    // use the shrinking heuristics
+	CacheSize   float64 // kernel cache size in MB
+	MaxIter     int     // maximal number of solver iterations; 0 means max(10000000, 100*l)
+
+	// Progress and Logger are called concurrently when the same Parameter trains several models at once,
+	// except by GridSearch and RandomSearch, which serialise the calls of their cross validations
+	Progress func(SolverProgress) bool // called by every solver run each min(l,1000) iterations; return false to stop early
+
+	Seed int64 // seed of the random folds of cross validation and probability estimates; 0 means a different seed every time
+
+	Parallelism int // maximal number of goroutines used by training, batch prediction and grid search; 0 means one per CPU
+
+	QuietMode bool         // no outputs (like LIBSVM's -q)
+	Logger    func(string) // receives the training and prediction output; nil prints to stdout
 }
 
+/**
+ * Returns the LIBSVM default parameters: C-SVC with an RBF kernel.
+ */
 func NewParameter() *Parameter {
 	return &Parameter{SvmType: C_SVC, KernelType: RBF, Degree: 3, Gamma: 0, Coef0: 0, Nu: 0.5, C: 1, Eps: 1e-3, P: 0.1,
-		NrWeight: 0, Probability: false}
+		NrWeight: 0, Probability: false, Shrinking: true, CacheSize: 100}
+}
+
+/**
+ * Returns a Logger that forwards output to an slog.Logger at Info level, one record per message.
+ * The "." and "*" progress markers of the solver are dropped.
+ */
+func SlogLogger(logger *slog.Logger) func(string) {
+	return func(msg string) {
+		msg = strings.TrimSpace(msg)
+		if strings.Trim(msg, ".*") == "" {
+			return
+		}
+		logger.Info(msg)
+	}
+}
+
+func (param *Parameter) info(format string, a ...interface{}) {
+	if param.QuietMode {
+		return
+	}
+	msg := fmt.Sprintf(format, a...)
+	if param.Logger != nil {
+		param.Logger(msg)
+	} else {
+		fmt.Print(msg)
+	}
+}
+
+/**
+ * Returns a new source of random numbers seeded with Seed, or with a seed from the global source if
+ * Seed is 0. Each training or cross validation draws from its own source, so runs with the same Seed
+ * give the same result even when they run concurrently.
+ */
+func (param *Parameter) newRand() *rand.Rand {
+	if param.Seed != 0 {
+		return rand.New(rand.NewSource(param.Seed))
+	}
+	return rand.New(rand.NewSource(rand.Int63()))
+}
+
+/**
+ * Checks whether the parameters are within the feasible range of the problem, like LIBSVM's svm_check_parameter.
+ * Returns nil if the parameters are feasible, otherwise an error describing the first problem found.
+ * If prob is nil only the checks that do not depend on the training data are done.
+ */
+func (param *Parameter) Validate(prob *Problem) error {
+	svmType := param.SvmType
+	if svmType != C_SVC && svmType != NU_SVC && svmType != ONE_CLASS && svmType != EPSILON_SVR && svmType != NU_SVR {
+		return fmt.Errorf("unknown svm type %d", svmType)
+	}
+
+	kernelType := param.KernelType
+	switch kernelType {
+	case LINEAR, POLY, RBF, SIGMOID, PRECOMPUTED:
+	default:
+		return fmt.Errorf("unknown kernel type %d", kernelType)
+	}
+
+	if (kernelType == POLY || kernelType == RBF || kernelType == SIGMOID) && param.Gamma < 0 {
+		return fmt.Errorf("gamma < 0 (gamma = %g)", param.Gamma)
+	}
+
+	if kernelType == POLY && param.Degree < 0 {
+		return fmt.Errorf("degree of polynomial kernel < 0 (degree = %d)", param.Degree)
+	}
+
+	if param.CacheSize <= 0 {
+		return fmt.Errorf("cache_size <= 0 (cache_size = %g)", param.CacheSize)
+	}
+
+	if param.MaxIter < 0 {
+		return fmt.Errorf("max_iter < 0 (max_iter = %d)", param.MaxIter)
+	}
+
+	if param.Eps <= 0 {
+		return fmt.Errorf("eps <= 0 (eps = %g)", param.Eps)
+	}
+
+	if (svmType == C_SVC || svmType == EPSILON_SVR || svmType == NU_SVR) && param.C <= 0 {
+		return fmt.Errorf("C <= 0 (C = %g)", param.C)
+	}
+
+	if (svmType == NU_SVC || svmType == ONE_CLASS || svmType == NU_SVR) && (param.Nu <= 0 || param.Nu > 1) {
+		return fmt.Errorf("nu <= 0 or nu > 1 (nu = %g)", param.Nu)
+	}
+
+	if svmType == EPSILON_SVR && param.P < 0 {
+		return fmt.Errorf("p < 0 (p = %g)", param.P)
+	}
+
+	if param.NrWeight < 0 || param.NrWeight > len(param.WeightLabel) || param.NrWeight > len(param.Weight) {
+		return fmt.Errorf("NrWeight = %d does not match len(WeightLabel) = %d and len(Weight) = %d",
+			param.NrWeight, len(param.WeightLabel), len(param.Weight))
+	}
+
+	if param.Probability && svmType == ONE_CLASS {
+		return fmt.Errorf("one-class SVM probability output not supported yet")
+	}
+
+	if prob == nil {
+		return nil
+	}
+
+	if prob.l == 0 {
+		return fmt.Errorf("problem has no instances")
+	}
+
+	if prob.w != nil && prob.sumWeights() <= 0 {
+		return fmt.Errorf("problem has no instances with positive weight")
+	}
+
+	if prob.labels != nil && svmType != C_SVC && svmType != NU_SVC {
+		return fmt.Errorf("string labels are only supported for classification (c_svc, nu_svc)")
+	}
+
+	if kernelType == PRECOMPUTED {
+		if err := prob.checkPrecomputed(); err != nil {
+			return err
+		}
+	}
+
+	// check whether nu-svc is feasible
+	if svmType == NU_SVC {
+		nrClass, label, _, count, _ := groupClasses(prob)
+		for i := 0; i < nrClass; i++ {
+			n1 := count[i]
+			for j := i + 1; j < nrClass; j++ {
+				n2 := count[j]
+				if param.Nu*float64(n1+n2)/2 > float64(mini(n1, n2)) {
+					return fmt.Errorf("specified nu %g is infeasible for classes %g (%d instances) and %g (%d instances)",
+						param.Nu, label[i], n1, label[j], n2)
+				}
+			}
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,74 @@
+package libSvm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParameterValidate(t *testing.T) {
+	prob := newTestProblem([]float64{1, 1, 1, -1}, [][]snode{
+		{{index: 1, value: 1}},
+		{{index: 1, value: 2}},
+		{{index: 1, value: 3}},
+		{{index: 1, value: -1}},
+	})
+
+	tests := []struct {
+		name  string
+		set   func(param *Parameter)
+		valid bool
+	}{
+		{"default", func(param *Parameter) {}, true},
+		{"negative gamma", func(param *Parameter) { param.Gamma = -1 }, false},
+		{"zero C", func(param *Parameter) { param.C = 0 }, false},
+		{"zero eps", func(param *Parameter) { param.Eps = 0 }, false},
+		{"nu > 1", func(param *Parameter) { param.SvmType = ONE_CLASS; param.Nu = 1.5 }, false},
+		{"nu <= 0", func(param *Parameter) { param.SvmType = NU_SVR; param.Nu = 0 }, false},
+		{"negative p", func(param *Parameter) { param.SvmType = EPSILON_SVR; param.P = -0.1 }, false},
+		{"weight mismatch", func(param *Parameter) { param.NrWeight = 1 }, false},
+		{"one-class probability", func(param *Parameter) { param.SvmType = ONE_CLASS; param.Probability = true }, false},
+		{"unknown kernel", func(param *Parameter) { param.KernelType = 7 }, false},
+		{"infeasible nu", func(param *Parameter) { param.SvmType = NU_SVC; param.Nu = 0.6 }, false},
+		{"feasible nu", func(param *Parameter) { param.SvmType = NU_SVC; param.Nu = 0.5 }, true},
+	}
+
+	for _, test := range tests {
+		param := NewParameter()
+		test.set(param)
+		err := param.Validate(prob)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestParameterLogger(t *testing.T) {
+	prob := newTestProblem([]float64{1, 1, -1, -1}, [][]snode{
+		{{index: 1, value: 1}},
+		{{index: 1, value: 2}},
+		{{index: 1, value: -1}},
+		{{index: 1, value: -2}},
+	})
+
+	var output []string
+	param := NewParameter()
+	param.Logger = func(msg string) { output = append(output, msg) }
+	if err := NewModel(param).Train(prob); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(output, ""), "optimization finished") {
+		t.Errorf("logger did not receive the solver output: %q", output)
+	}
+
+	output = nil
+	param.QuietMode = true
+	if err := NewModel(param).Train(prob); err != nil {
+		t.Fatal(err)
+	}
+	if len(output) != 0 {
+		t.Errorf("quiet mode produced output: %q", output)
+	}
+}
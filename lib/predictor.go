@@ -1,4 +1,4 @@
-package main
+package libSvm
 
 // import "fmt" // DEBUG
 
@@ -23,7 +23,7 @@ package main
    the returned value is +1/-1.
 
 */
-func (model Model) PredictValues(x map[int]float64) (returnValue float64, decisionValues []float64) {
+func (model *Model) PredictValues(x map[int]float64) (returnValue float64, decisionValues []float64) {
 	returnValue = 0
 
 	px := MapToSnode(x)
@@ -120,7 +120,7 @@ func (model Model) PredictValues(x map[int]float64) (returnValue float64, decisi
 			}
 		}
 
-		returnValue = float64(model.label[maxIdx])
+		returnValue = model.label[maxIdx]
 		return // returnValue, decisionValues
 	}
 
@@ -137,9 +137,17 @@ func (model Model) PredictValues(x map[int]float64) (returnValue float64, decisi
    returned.
 
 */
-func (model Model) Predict(x map[int]float64) float64 {
+func (model *Model) Predict(x map[int]float64) float64 {
 
 	predict, _ := model.PredictValues(x)
 
 	return predict
 }
+
+/**
+ * Same as Predict, but returns the original label: the string label if the model was trained on
+ * string labels, the formatted prediction otherwise.
+ */
+func (model *Model) PredictLabel(x map[int]float64) string {
+	return model.labelName(model.Predict(x))
+}
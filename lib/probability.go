@@ -1,7 +1,7 @@
-package main
+package libSvm
 
 import (
-	"fmt"
+	"context"
 	"math"
 	"math/rand"
 )
@@ -18,48 +18,65 @@ import (
    is nil and the returned value is the same as that of Predict.
 
 */
-func (model Model) PredictProbability(x map[int]float64) (returnValue float64, probabilityEstimate []float64) {
+func (model *Model) PredictProbability(x map[int]float64) (returnValue float64, probabilityEstimate []float64) {
 
 	if (model.param.SvmType == C_SVC || model.param.SvmType == NU_SVC) &&
 		model.probA != nil && model.probB != nil {
 
-		var nrClass int = model.nrClass
 		_, decisionValues := model.PredictValues(x)
+		returnValue, probabilityEstimate = model.probabilityFromDecisionValues(decisionValues)
+		return // returnValue, probabilityEstimates
+	} else {
+		probabilityEstimate = nil
+		returnValue = model.Predict(x)
+		return // returnValue, probabilityEstimates
+	}
 
-		var minProb float64 = 1e-7
+}
 
-		pairWiseProb := make([][]float64, nrClass)
-		for i := 0; i < nrClass; i++ {
-			pairWiseProb[i] = make([]float64, nrClass)
-		}
+/**
+ * Computes the class probabilities of a classification model with probability information
+ * from the decision values of PredictValues
+ */
+func (model *Model) probabilityFromDecisionValues(decisionValues []float64) (float64, []float64) {
+	var nrClass int = model.nrClass
 
-		var k int = 0
-		for i := 0; i < nrClass; i++ {
-			for j := i + 1; j < nrClass; j++ {
-				m := maxf(sigmoidPredict(decisionValues[k], model.probA[k], model.probB[k]), minProb)
-				pairWiseProb[i][j] = minf(m, 1-minProb)
-				pairWiseProb[j][i] = 1 - pairWiseProb[i][j]
-				k++
-			}
-		}
+	var minProb float64 = 1e-7
 
-		probabilityEstimate = multiClassProbability(nrClass, pairWiseProb)
+	pairWiseProb := make([][]float64, nrClass)
+	for i := 0; i < nrClass; i++ {
+		pairWiseProb[i] = make([]float64, nrClass)
+	}
 
-		var maxIdx int = 0
-		for i := 1; i < nrClass; i++ {
-			if probabilityEstimate[i] > probabilityEstimate[maxIdx] {
-				maxIdx = i
-			}
+	var k int = 0
+	for i := 0; i < nrClass; i++ {
+		for j := i + 1; j < nrClass; j++ {
+			m := maxf(sigmoidPredict(decisionValues[k], model.probA[k], model.probB[k]), minProb)
+			pairWiseProb[i][j] = minf(m, 1-minProb)
+			pairWiseProb[j][i] = 1 - pairWiseProb[i][j]
+			k++
 		}
+	}
 
-		returnValue = float64(model.label[maxIdx])
-		return // returnValue, probabilityEstimates
-	} else {
-		probabilityEstimate = nil
-		returnValue = model.Predict(x)
-		return // returnValue, probabilityEstimates
+	probabilityEstimate := multiClassProbability(nrClass, pairWiseProb, model.param)
+
+	var maxIdx int = 0
+	for i := 1; i < nrClass; i++ {
+		if probabilityEstimate[i] > probabilityEstimate[maxIdx] {
+			maxIdx = i
+		}
 	}
 
+	return model.label[maxIdx], probabilityEstimate
+}
+
+/**
+ * Same as PredictProbability, but returns the original label: the string label if the model was
+ * trained on string labels, the formatted prediction otherwise.
+ */
+func (model *Model) PredictProbabilityLabel(x map[int]float64) (string, []float64) {
+	predict, probabilityEstimate := model.PredictProbability(x)
+	return model.labelName(predict), probabilityEstimate
 }
 
 func sigmoidPredict(decisionValue, A, B float64) float64 {
@@ -71,7 +88,7 @@ func sigmoidPredict(decisionValue, A, B float64) float64 {
 	}
 }
 
-func multiClassProbability(k int, r [][]float64) []float64 {
+func multiClassProbability(k int, r [][]float64, param *Parameter) []float64 {
 	p := make([]float64, k)
 
 	Q := make([][]float64, k)
@@ -131,7 +148,7 @@ func multiClassProbability(k int, r [][]float64) []float64 {
 	}
 
 	if iter >= maxIter {
-		fmt.Println("Exceeds max_iter in multiclass_prob")
+		param.info("Exceeds max_iter in multiclass_prob\n")
 	}
 
 	return p
@@ -139,9 +156,9 @@ func multiClassProbability(k int, r [][]float64) []float64 {
 
 /**
  * Cross-validation decision values for probability estimates
- * @return probA, probB
+ * @return probA, probB, err
  */
-func binarySvcProbability(prob *Problem, param *Parameter, Cp, Cn float64) (probA float64, probB float64) {
+func binarySvcProbability(ctx context.Context, prob *Problem, param *Parameter, Cp, Cn float64, rng *rand.Rand) (probA float64, probB float64, err error) {
 	var nrFold int = 5
 	perm := make([]int, prob.l)
 	decisionValues := make([]float64, prob.l)
@@ -150,7 +167,7 @@ func binarySvcProbability(prob *Problem, param *Parameter, Cp, Cn float64) (prob
 		perm[i] = i
 	}
 	for i := 0; i < prob.l; i++ {
-		j := i + rand.Intn(prob.l-i)
+		j := i + rng.Intn(prob.l-i)
 		perm[i], perm[j] = perm[j], perm[i]
 	}
 
@@ -160,19 +177,30 @@ func binarySvcProbability(prob *Problem, param *Parameter, Cp, Cn float64) (prob
 
 		var subProb Problem
 
+		subProb.xSpace = prob.xSpace // inherits the space
 		subProb.l = prob.l - (end - begin)
 		subProb.x = make([]int, subProb.l)
 		subProb.y = make([]float64, subProb.l)
 
+		if prob.w != nil {
+			subProb.w = make([]float64, subProb.l)
+		}
+
 		var k int = 0
 		for j := 0; j < begin; j++ {
 			subProb.x[k] = prob.x[perm[j]]
 			subProb.y[k] = prob.y[perm[j]]
+			if prob.w != nil {
+				subProb.w[k] = prob.w[perm[j]]
+			}
 			k++
 		}
 		for j := end; j < prob.l; j++ {
 			subProb.x[k] = prob.x[perm[j]]
 			subProb.y[k] = prob.y[perm[j]]
+			if prob.w != nil {
+				subProb.w[k] = prob.w[perm[j]]
+			}
 			k++
 		}
 
@@ -201,6 +229,7 @@ func binarySvcProbability(prob *Problem, param *Parameter, Cp, Cn float64) (prob
 		} else {
 			subParam := *param
 			subParam.Probability = false
+			subParam.Balanced = false // Cp and Cn already include the class weights
 			subParam.C = 1
 			subParam.NrWeight = 2
 			subParam.WeightLabel = make([]int, 2)
@@ -210,21 +239,23 @@ func binarySvcProbability(prob *Problem, param *Parameter, Cp, Cn float64) (prob
 			subParam.Weight[0] = Cp
 			subParam.Weight[1] = Cn
 			subModel := NewModel(&subParam)
-			subModel.Train(&subProb)
+			if err = subModel.TrainContext(ctx, &subProb); !isUsableModel(err) {
+				return 0, 0, err
+			}
 			for j := begin; j < end; j++ {
 				idx := prob.x[perm[j]]
 				x := SnodeToMap(prob.xSpace[idx:])
 				_, subProbDecision := subModel.PredictValues(x)
-				decisionValues[perm[j]] = subProbDecision[0] * float64(subModel.label[0])
+				decisionValues[perm[j]] = subProbDecision[0] * subModel.label[0]
 			}
 		}
 	}
 
-	probA, probB = sigmoidTrain(prob.l, decisionValues, prob.y)
-	return // probA, probB
+	probA, probB = sigmoidTrain(prob.l, decisionValues, prob.y, param)
+	return probA, probB, nil
 }
 
-func sigmoidTrain(l int, decisionValues, labels []float64) (probA float64, probB float64) {
+func sigmoidTrain(l int, decisionValues, labels []float64, param *Parameter) (probA float64, probB float64) {
 	var prior1 float64 = 0
 	var prior0 float64 = 0
 	probA = 0
@@ -332,14 +363,14 @@ func sigmoidTrain(l int, decisionValues, labels []float64) (probA float64, probB
 		}
 
 		if stepsize < minStep {
-			fmt.Printf("Line search fails in two-class probability estimates\n")
+			param.info("Line search fails in two-class probability estimates\n")
 			break
 		}
 
 	}
 
 	if iter >= maxIter {
-		fmt.Printf("Reaching maximal iterations in two-class probability estimates\n")
+		param.info("Reaching maximal iterations in two-class probability estimates\n")
 	}
 
 	return // probA, probB
@@ -348,14 +379,17 @@ func sigmoidTrain(l int, decisionValues, labels []float64) (probA float64, probB
 /**
  * Return parameter of a Laplace distribution
  */
-func svrProbability(prob *Problem, param *Parameter) float64 {
+func svrProbability(ctx context.Context, prob *Problem, param *Parameter, rng *rand.Rand) (float64, error) {
 	var nrFold int = 5
 	var mae float64 = 0
 
 	var newParam Parameter = *param
 	newParam.Probability = false
 
-	ymv := CrossValidation(prob, &newParam, nrFold)
+	ymv, err := CrossValidationRand(ctx, prob, &newParam, nrFold, rng)
+	if err != nil {
+		return 0, err
+	}
 
 	for i := 0; i < prob.l; i++ {
 		ymv[i] = prob.y[i] - ymv[i]
@@ -375,7 +409,7 @@ func svrProbability(prob *Problem, param *Parameter) float64 {
 		}
 	}
 	mae /= float64(prob.l - count)
-	fmt.Printf("Prob. model for test data: target value = predicted value + z,\nz: Laplace distribution e^(-|z|/sigma)/(2sigma),sigma= %g\n", mae)
+	param.info("Prob. model for test data: target value = predicted value + z,\nz: Laplace distribution e^(-|z|/sigma)/(2sigma),sigma= %g\n", mae)
 
-	return mae
+	return mae, nil
 }
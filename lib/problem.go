@@ -1,9 +1,12 @@
-package main
+package libSvm
 
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -13,14 +16,37 @@ type snode struct {
 	value float64 // coeff
 }
 
+/**
+ * Problem is a set of labelled training or test instances stored in the sparse LIBSVM layout.
+ */
 type Problem struct {
-	l      int       // #SVs
-	y      []float64 // labels
-	x      []int     // starting indices in xSpace defining SVs
-	xSpace []snode   // SV coeffs
-	i      int       // counter for iterator
+	l      int           // #SVs
+	y      []float64     // labels
+	x      []int         // starting indices in xSpace defining SVs
+	xSpace []snode       // SV coeffs
+	labels *LabelEncoder // string labels; nil if the labels are numeric
+	w      []float64     // instance weights scaling C; nil if all instances have weight 1
+	i      int           // counter for iterator
 }
 
+/**
+ * ParseError reports the position of malformed input; Line and Column are 1-based.
+ */
+type ParseError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+/**
+ * Reads the problem from the specified file in LIBSVM format. If param.Gamma is 0 it is set to
+ * 1/num_features. gzip and bzip2 compressed files are decompressed on the fly.
+ */
 func (problem *Problem) Read(file string, param *Parameter) error { // reads the problem from the specified file
 	f, err := os.Open(file)
 	if err != nil {
@@ -29,53 +55,292 @@ func (problem *Problem) Read(file string, param *Parameter) error { // reads the
 
 	defer f.Close() // close f on method return
 
-	scanner := bufio.NewScanner(f)
-	var max_idx int = 0
-	var l int = 0
-	//var j int = 0
+	read, err := ReadProblem(f)
+	if err != nil {
+		return err
+	}
+	*problem = *read
+
+	if param.KernelType == PRECOMPUTED {
+		if err := problem.checkPrecomputed(); err != nil {
+			return err
+		}
+	} else if max_idx := problem.MaxIndex(); param.Gamma == 0 && max_idx > 0 {
+		param.Gamma = 1.0 / float64(max_idx)
+	}
+
+	return nil
+}
+
+/**
+ * Reads a problem in LIBSVM format ("label index:value ...") from r, e.g. an opened file, os.Stdin,
+ * a gzip.Reader or an HTTP response body. Blank lines and everything after a '#' are skipped.
+ * Feature indices that are not in ascending order are sorted; duplicate indices are an error.
+ * If any label is not a number, all labels are read as strings and encoded with a LabelEncoder.
+ * An instance weight can be given as a "w:weight" token right after the label; instances without
+ * one have weight 1.
+ * Malformed input is reported with a *ParseError; the file name is taken from r if it has a Name
+ * method (like *os.File), otherwise it is "input". gzip and bzip2 compressed input is detected by
+ * its magic bytes and decompressed on the fly.
+ */
+func ReadProblem(r io.Reader) (*Problem, error) {
+	var name string = "input"
+	if named, ok := r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+
+	r, err := decompress(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	problem := &Problem{}
+	var rawLabels []string
+	var weights []float64
+	var hasWeights bool = false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, bufio.MaxScanTokenSize), math.MaxInt32) // precomputed kernel rows can be very long
+	var lineNr int = 0
 	for scanner.Scan() {
-		problem.x = append(problem.x, len(problem.xSpace))
+		lineNr++
 		line := scanner.Text()
-		lineSansComments := strings.Split(line, "#")  // remove any comments
-		tokens := strings.Fields(lineSansComments[0]) // get all the word tokens (seperated by white spaces)
-		if label, err := strconv.ParseFloat(tokens[0], 64); err == nil {
-			problem.y = append(problem.y, label)
-		} else {
-			return fmt.Errorf("Fail to parse label\n")
-		}
-
-		space := tokens[1:]
-		for _, w := range space {
-			if len(w) > 0 {
-				node := strings.Split(w, ":")
-				if len(node) > 1 {
-					var index int
-					var value float64
-					if index, err = strconv.Atoi(node[0]); err != nil {
-						return fmt.Errorf("Fail to parse index from token %v\n", w)
-					}
-					if value, err = strconv.ParseFloat(node[1], 64); err != nil {
-						return fmt.Errorf("Fail to parse value from token %v\n", w)
-					}
-					problem.xSpace = append(problem.xSpace, snode{index: index, value: value})
-					if index > max_idx {
-						max_idx = index
-					}
+		if k := strings.IndexByte(line, '#'); k >= 0 {
+			line = line[:k] // remove any comments
+		}
 
+		fields := tokenize(line)
+		if len(fields) == 0 {
+			continue // blank or comment line
+		}
+
+		parseError := func(column int, format string, a ...interface{}) error {
+			return &ParseError{File: name, Line: lineNr, Column: column, Msg: fmt.Sprintf(format, a...)}
+		}
+
+		features := fields[1:]
+		var weight float64 = 1
+		if len(features) > 0 && strings.HasPrefix(features[0].text, "w:") { // instance weight extension
+			if weight, err = strconv.ParseFloat(features[0].text[2:], 64); err != nil || weight < 0 {
+				return nil, parseError(features[0].column+2, "invalid instance weight %q", features[0].text[2:])
+			}
+			hasWeights = true
+			features = features[1:]
+		}
+
+		nodes := make([]snode, 0, len(features))
+		columns := make([]int, 0, len(features))
+		var sorted bool = true
+		for _, w := range features {
+			colon := strings.IndexByte(w.text, ':')
+			if colon < 0 {
+				return nil, parseError(w.column, "expected index:value, found %q", w.text)
+			}
+			index, err := strconv.Atoi(w.text[:colon])
+			if err != nil || index < 0 {
+				return nil, parseError(w.column, "invalid feature index %q", w.text[:colon])
+			}
+			value, err := strconv.ParseFloat(w.text[colon+1:], 64)
+			if err != nil {
+				return nil, parseError(w.column+colon+1, "invalid feature value %q", w.text[colon+1:])
+			}
+
+			if n := len(nodes); n > 0 && nodes[n-1].index >= index {
+				sorted = false
+			}
+			nodes = append(nodes, snode{index: index, value: value})
+			columns = append(columns, w.column)
+		}
+
+		if !sorted {
+			order := make([]int, len(nodes))
+			for k := range order {
+				order[k] = k
+			}
+			sort.SliceStable(order, func(a, b int) bool { return nodes[order[a]].index < nodes[order[b]].index })
+			for k := 1; k < len(order); k++ {
+				if nodes[order[k]].index == nodes[order[k-1]].index {
+					return nil, parseError(columns[order[k]], "duplicate feature index %d", nodes[order[k]].index)
 				}
 			}
+			sortedNodes := make([]snode, len(nodes))
+			for k := range order {
+				sortedNodes[k] = nodes[order[k]]
+			}
+			nodes = sortedNodes
 		}
 
+		problem.x = append(problem.x, len(problem.xSpace))
+		rawLabels = append(rawLabels, fields[0].text)
+		weights = append(weights, weight)
+		problem.xSpace = append(problem.xSpace, nodes...)
 		problem.xSpace = append(problem.xSpace, snode{index: -1})
-		l++
+		problem.l++
 	}
-	problem.l = l
 
-	if param.Gamma == 0 && max_idx > 0 {
-		param.Gamma = 1.0 / float64(max_idx)
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%d: %v", name, lineNr+1, err)
+	}
+
+	problem.y, problem.labels = parseLabels(rawLabels)
+	if hasWeights {
+		problem.w = weights
+	}
+
+	return problem, nil
+}
+
+type token struct {
+	text   string
+	column int // 1-based byte column of the first character
+}
+
+/**
+ * Splits a line into white space separated tokens and remembers where each token starts
+ */
+func tokenize(line string) []token {
+	var tokens []token
+	var begin int = -1
+	for k := 0; k <= len(line); k++ {
+		if k == len(line) || line[k] == ' ' || line[k] == '\t' || line[k] == '\r' {
+			if begin >= 0 {
+				tokens = append(tokens, token{text: line[begin:k], column: begin + 1})
+				begin = -1
+			}
+		} else if begin < 0 {
+			begin = k
+		}
+	}
+	return tokens
+}
+
+/**
+ * Returns a problem built from dense feature vectors: X[i][j] becomes feature j+1 of instance i.
+ * Zero values are left out, as in the LIBSVM sparse format.
+ */
+func NewProblemFromDense(y []float64, X [][]float64) (*Problem, error) {
+	if len(y) != len(X) {
+		return nil, fmt.Errorf("Number of labels %d does not match number of instances %d\n", len(y), len(X))
+	}
+
+	problem := &Problem{}
+	for i := range X {
+		problem.x = append(problem.x, len(problem.xSpace))
+		problem.y = append(problem.y, y[i])
+		for j, value := range X[i] {
+			if value != 0 {
+				problem.xSpace = append(problem.xSpace, snode{index: j + 1, value: value})
+			}
+		}
+		problem.xSpace = append(problem.xSpace, snode{index: -1})
+		problem.l++
+	}
+
+	return problem, nil
+}
+
+/**
+ * Returns a problem built from sparse feature vectors mapping feature index to value, like the
+ * instances taken by Model.Predict.
+ */
+func NewProblemFromSparse(y []float64, X []map[int]float64) (*Problem, error) {
+	if len(y) != len(X) {
+		return nil, fmt.Errorf("Number of labels %d does not match number of instances %d\n", len(y), len(X))
+	}
+
+	problem := &Problem{}
+	for i := range X {
+		if err := problem.AddInstance(y[i], X[i]); err != nil {
+			return nil, fmt.Errorf("instance %d: %v", i+1, err)
+		}
+	}
+
+	return problem, nil
+}
+
+/**
+ * Same as ReadProblem, but encodes the labels with the codes of labels, see SetLabelEncoder.
+ */
+func ReadProblemWithLabels(r io.Reader, labels *LabelEncoder) (*Problem, error) {
+	problem, err := ReadProblem(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := problem.SetLabelEncoder(labels); err != nil {
+		return nil, err
+	}
+	return problem, nil
+}
+
+/**
+ * Appends an instance with label y and features x (feature index to value) to the problem.
+ * Feature indices start at 1; index 0 is only used for the serial number of a precomputed kernel row.
+ * If the problem has instance weights, the new instance gets weight 1.
+ */
+func (problem *Problem) AddInstance(y float64, x map[int]float64) error {
+	for index := range x {
+		if index < 0 {
+			return fmt.Errorf("Invalid feature index %d\n", index)
+		}
+	}
+
+	problem.x = append(problem.x, len(problem.xSpace))
+	problem.y = append(problem.y, y)
+	if problem.w != nil {
+		problem.w = append(problem.w, 1)
 	}
+	problem.xSpace = append(problem.xSpace, MapToSnode(x)...)
+	problem.l++
 
-	return scanner.Err()
+	return nil
+}
+
+/**
+ * Returns the largest feature index of the problem. Problems that are not read with Read leave
+ * Parameter.Gamma alone; LIBSVM's default is 1/MaxIndex().
+ */
+func (problem *Problem) MaxIndex() int {
+	var max_idx int = 0
+	for i := 0; i < problem.l; i++ {
+		for idx := problem.x[i]; problem.xSpace[idx].index != -1; idx++ {
+			max_idx = maxi(max_idx, problem.xSpace[idx].index)
+		}
+	}
+	return max_idx
+}
+
+/**
+ * Checks that every instance is a row of a precomputed kernel matrix: "0:serial_number" followed by
+ * the dense kernel values 1:K(x,x_1) ... n:K(x,x_n), where every serial number is in [1,n].
+ */
+func (problem *Problem) checkPrecomputed() error {
+	var maxSerial int = 0
+	for i := 0; i < problem.l; i++ {
+		first := problem.xSpace[problem.x[i]]
+		if first.index != 0 {
+			return fmt.Errorf("Wrong input format at instance %d: first column must be 0:sample_serial_number", i+1)
+		}
+		serial := int(first.value)
+		if serial <= 0 || float64(serial) != first.value {
+			return fmt.Errorf("Wrong input format at instance %d: sample_serial_number %g must be a positive integer", i+1, first.value)
+		}
+		maxSerial = maxi(maxSerial, serial)
+	}
+
+	for i := 0; i < problem.l; i++ {
+		row := problem.xSpace[problem.x[i]:]
+		var k int = 0
+		for ; row[k].index != -1; k++ {
+			if row[k].index != k {
+				return fmt.Errorf("Wrong input format at instance %d: kernel values must be dense, expected index %d but found %d", i+1, k, row[k].index)
+			}
+		}
+		if k <= maxSerial {
+			return fmt.Errorf("Wrong input format at instance %d: sample_serial_number %d out of range, the row has %d kernel values", i+1, maxSerial, k-1)
+		}
+	}
+
+	return nil
 }
 
 func (problem *Problem) Begin() {
@@ -101,6 +366,155 @@ func (problem *Problem) Get() (y float64, x map[int]float64) {
 	return // y, x
 }
 
+/**
+ * Returns the encoder of the string labels, or nil if the labels are numeric. Get then returns the
+ * encoded label; use Decode to get the original one.
+ */
+func (problem *Problem) LabelEncoder() *LabelEncoder {
+	return problem.labels
+}
+
+/**
+ * Re-encodes the labels of the problem with the codes of labels, e.g. the LabelEncoder of the model a
+ * test problem is predicted with, so that Get, Evaluate* and the model agree on the code of each class.
+ * String labels are encoded independently in each file, in the order they first appear, so the codes
+ * of two files read separately do not match otherwise. Labels that labels does not know get new codes
+ * that no model class has; labels itself is not modified.
+ *
+ * A nil labels means the model has numeric labels: the labels of the problem must then be numbers.
+ */
+func (problem *Problem) SetLabelEncoder(labels *LabelEncoder) error {
+	if labels == nil {
+		if problem.labels == nil {
+			return nil
+		}
+		y := make([]float64, problem.l)
+		for i := 0; i < problem.l; i++ {
+			var err error
+			if y[i], err = strconv.ParseFloat(problem.labels.Decode(problem.y[i]), 64); err != nil {
+				return fmt.Errorf("Fail to encode labels: %q is not a numeric label\n", problem.labels.Decode(problem.y[i]))
+			}
+		}
+		problem.y, problem.labels = y, nil
+		return nil
+	}
+
+	encoder := labels.clone()
+	for i := 0; i < problem.l; i++ {
+		problem.y[i] = encoder.Encode(problem.labelName(problem.y[i]))
+	}
+	problem.labels = encoder
+	return nil
+}
+
+/**
+ * Sets the instance weights: the penalty C of instance i is multiplied by w[i]. Instances with weight 0
+ * are left out of training. A nil w gives every instance weight 1.
+ */
+func (problem *Problem) SetWeights(w []float64) error {
+	if w == nil {
+		problem.w = nil
+		return nil
+	}
+	if len(w) != problem.l {
+		return fmt.Errorf("Number of weights %d does not match number of instances %d\n", len(w), problem.l)
+	}
+	for i, weight := range w {
+		if weight < 0 || math.IsNaN(weight) {
+			return fmt.Errorf("Invalid weight %g of instance %d\n", weight, i+1)
+		}
+	}
+	problem.w = append([]float64(nil), w...)
+	return nil
+}
+
+/**
+ * Returns the instance weights, or nil if all instances have weight 1.
+ */
+func (problem *Problem) Weights() []float64 {
+	return problem.w
+}
+
+/**
+ * Reads the instance weights from r, one weight per line in the order of the instances, like the
+ * weight files of LIBSVM's instance weight extension (svm-train -W).
+ */
+func (problem *Problem) ReadWeights(r io.Reader) error {
+	var name string = "input"
+	if named, ok := r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+
+	w := make([]float64, 0, problem.l)
+	scanner := bufio.NewScanner(r)
+	var lineNr int = 0
+	for scanner.Scan() {
+		lineNr++
+		fields := tokenize(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		weight, err := strconv.ParseFloat(fields[0].text, 64)
+		if err != nil || weight < 0 {
+			return &ParseError{File: name, Line: lineNr, Column: fields[0].column, Msg: fmt.Sprintf("invalid weight %q", fields[0].text)}
+		}
+		w = append(w, weight)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return problem.SetWeights(w)
+}
+
+func (problem *Problem) weight(i int) float64 {
+	if problem.w == nil {
+		return 1
+	}
+	return problem.w[i]
+}
+
+func (problem *Problem) sumWeights() float64 {
+	if problem.w == nil {
+		return float64(problem.l)
+	}
+	var sum float64 = 0
+	for _, weight := range problem.w {
+		sum += weight
+	}
+	return sum
+}
+
+/**
+ * Returns the problem without the instances of weight 0, which cannot be support vectors, and the
+ * original index of each remaining instance. Returns problem itself and nil if there is nothing to remove.
+ */
+func (problem *Problem) removeZeroWeight() (*Problem, []int) {
+	var nrZero int = 0
+	for i := 0; i < problem.l && problem.w != nil; i++ {
+		if problem.w[i] == 0 {
+			nrZero++
+		}
+	}
+	if nrZero == 0 {
+		return problem, nil
+	}
+
+	reduced := &Problem{xSpace: problem.xSpace, labels: problem.labels} // inherits the space
+	kept := make([]int, 0, problem.l-nrZero)
+	for i := 0; i < problem.l; i++ {
+		if problem.w[i] > 0 {
+			reduced.x = append(reduced.x, problem.x[i])
+			reduced.y = append(reduced.y, problem.y[i])
+			reduced.w = append(reduced.w, problem.w[i])
+			kept = append(kept, i)
+		}
+	}
+	reduced.l = len(kept)
+
+	return reduced, kept
+}
+
 func (problem *Problem) ProblemSize() int {
 	return problem.l
 }
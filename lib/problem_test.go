@@ -0,0 +1,174 @@
+package libSvm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewProblemFromDenseAndSparse(t *testing.T) {
+	y := []float64{1, -1, 1}
+	dense, err := NewProblemFromDense(y, [][]float64{
+		{0.5, 0, 2},
+		{0, 0, -1},
+		{1.5, 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sparse, err := NewProblemFromSparse(y, []map[int]float64{
+		{3: 2, 1: 0.5},
+		{3: -1},
+		{1: 1.5, 2: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := newTestProblem(y, [][]snode{
+		{{index: 1, value: 0.5}, {index: 3, value: 2}},
+		{{index: 3, value: -1}},
+		{{index: 1, value: 1.5}, {index: 2, value: 3}},
+	})
+
+	for name, prob := range map[string]*Problem{"dense": dense, "sparse": sparse} {
+		if prob.l != want.l || !reflect.DeepEqual(prob.y, want.y) ||
+			!reflect.DeepEqual(prob.x, want.x) || !reflect.DeepEqual(prob.xSpace, want.xSpace) {
+			t.Errorf("%s problem = %+v, want %+v", name, prob, want)
+		}
+		if prob.MaxIndex() != 3 {
+			t.Errorf("%s problem: MaxIndex() = %d, want 3", name, prob.MaxIndex())
+		}
+	}
+
+	if err := sparse.AddInstance(-1, map[int]float64{2: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if sparse.ProblemSize() != 4 {
+		t.Errorf("ProblemSize() = %d after AddInstance, want 4", sparse.ProblemSize())
+	}
+
+	if _, err := NewProblemFromDense([]float64{1}, nil); err == nil {
+		t.Error("label/instance count mismatch was accepted")
+	}
+	if err := sparse.AddInstance(1, map[int]float64{-2: 1}); err == nil {
+		t.Error("negative feature index was accepted")
+	}
+}
+
+func TestReadProblem(t *testing.T) {
+	input := "# a comment line\n" +
+		"1 3:0.5 1:2 # out of order\n" +
+		"\n" +
+		"   \t\n" +
+		"-1 2:-1\r\n"
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(input))
+	zw.Close()
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prob, err := ReadProblem(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := newTestProblem([]float64{1, -1}, [][]snode{
+		{{index: 1, value: 2}, {index: 3, value: 0.5}},
+		{{index: 2, value: -1}},
+	})
+	if !reflect.DeepEqual(prob.y, want.y) || !reflect.DeepEqual(prob.x, want.x) || !reflect.DeepEqual(prob.xSpace, want.xSpace) {
+		t.Errorf("ReadProblem = %+v, want %+v", prob, want)
+	}
+
+	for _, c := range []struct {
+		input        string
+		line, column int
+	}{
+		{"1 1:1\n-1 1:1 :2\n", 2, 8},
+		{"1 1:1 2:1\n\n-1 1:1 2\n", 3, 8},
+		{"1 1:1 a:1\n", 1, 7},
+		{"1 1:1 2:z\n", 1, 9},
+		{"1 2:1 1:1 2:3\n", 1, 11},
+	} {
+		_, err := ReadProblem(strings.NewReader(c.input))
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: error = %v, want *ParseError", c.input, err)
+			continue
+		}
+		if parseErr.File != "input" || parseErr.Line != c.line || parseErr.Column != c.column {
+			t.Errorf("%q: error at %s:%d:%d, want input:%d:%d", c.input, parseErr.File, parseErr.Line, parseErr.Column, c.line, c.column)
+		}
+	}
+}
+
+func TestReadWeights(t *testing.T) {
+	prob, err := ReadProblem(strings.NewReader("1 w:2 1:1\n-1 2:1\n1 w:0.5 3:1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prob.Weights(), []float64{2, 1, 0.5}) {
+		t.Errorf("weights from w: tokens = %v, want [2 1 0.5]", prob.Weights())
+	}
+
+	var buf bytes.Buffer
+	if err := prob.WriteLIBSVM(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "1 w:2 1:1\n-1 w:1 2:1\n1 w:0.5 3:1\n" {
+		t.Errorf("WriteLIBSVM = %q", got)
+	}
+
+	if err := prob.ReadWeights(strings.NewReader("3\n\n0\n1.5\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prob.Weights(), []float64{3, 0, 1.5}) {
+		t.Errorf("weights from weight file = %v, want [3 0 1.5]", prob.Weights())
+	}
+
+	if err := prob.ReadWeights(strings.NewReader("1\n2\n")); err == nil {
+		t.Error("weight file with too few weights was accepted")
+	}
+	if err := prob.ReadWeights(strings.NewReader("1\n-2\n1\n")); err == nil {
+		t.Error("negative weight was accepted")
+	}
+	if _, err := ReadProblem(strings.NewReader("1 w:x 1:1\n")); err == nil {
+		t.Error("invalid w: token was accepted")
+	}
+}
+
+func TestAddInstanceAfterSetWeights(t *testing.T) {
+	prob, err := NewProblemFromDense([]float64{1, -1}, [][]float64{{1, 0}, {0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prob.SetWeights([]float64{2, 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := prob.AddInstance(1, map[int]float64{1: 0.9, 2: 0.1}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prob.Weights(), []float64{2, 0.5, 1}) {
+		t.Errorf("weights = %v, want [2 0.5 1]", prob.Weights())
+	}
+
+	param := NewParameter()
+	param.Gamma = 0.5
+	param.QuietMode = true
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+	if got := model.Predict(map[int]float64{1: 1}); got != 1 {
+		t.Errorf("Predict = %g, want 1", got)
+	}
+}
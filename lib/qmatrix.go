@@ -1,9 +1,10 @@
-package main
+package libSvm
 
 type matrixQ interface {
-	getQ(i, l int) []float64   // Returns all the Q matrix values for column i
+	getQ(i, l int) []float64   // Returns the Q matrix values for rows [0,l) of column i
 	getQD() []float64          // Returns the Q matrix values for the diagonal
 	computeQ(i, j int) float64 // Returns the Q matrix value at (i,j)
+	swapIndex(i, j int)        // Swaps rows and columns i and j (used by shrinking)
 	showCacheStats()
 }
 
@@ -31,10 +32,10 @@ func (q *svcQ) getQD() []float64 {
 func (q *svcQ) getQ(i, l int) []float64 {
 	// rcq := make([]float64, l)
 
-	rcq, newData := q.colCache.getData(i)
-	if newData {
+	rcq, from := q.colCache.getData(i, l)
+	if from < l { // rows [from,l) are not in the cache yet
 		run := func(start, end int) {
-			for j := start; j < end; j++ { // compute rows
+			for j := maxi(start, from); j < end && j < l; j++ { // compute rows
 				rcq[j] = float64(q.y[i]*q.y[j]) * q.kernel.compute(i, j)
 			}
 		}
@@ -53,6 +54,16 @@ func (q *svcQ) computeQ(i, j int) float64 {
 	return float64(q.y[i]*q.y[j]) * q.kernel.compute(i, j)
 }
 
+/**
+ * Swaps rows and columns i and j
+ */
+func (q *svcQ) swapIndex(i, j int) {
+	q.colCache.swapIndex(i, j)
+	q.kernel.swapIndex(i, j)
+	q.y[i], q.y[j] = q.y[j], q.y[i]
+	q.qd[i], q.qd[j] = q.qd[j], q.qd[i]
+}
+
 /**
  * Prints out the cache performance statistics
  */
@@ -71,7 +82,10 @@ func NewSVCQ(prob *Problem, param *Parameter, y []int8) *svcQ {
 		qd[i] = kernel.compute(i, i)
 	}
 
-	return &svcQ{y: y, qd: qd, kernel: kernel, parRunner: NewParallelRunner(prob.l), colCache: NewCache(prob.l, prob.l)}
+	yCopy := make([]int8, prob.l) // swapIndex() reorders y, so do not share it with the solver
+	copy(yCopy, y)
+
+	return &svcQ{y: yCopy, qd: qd, kernel: kernel, parRunner: newParallelRunnerLimit(prob.l, param.Parallelism), colCache: NewCache(prob.l, prob.l, param)}
 }
 
 /**
@@ -103,10 +117,10 @@ func (q *oneClassQ) getQ(i, l int) []float64 {
 			}
 	*/
 
-	rcq, newData := q.colCache.getData(i)
-	if newData {
+	rcq, from := q.colCache.getData(i, l)
+	if from < l { // rows [from,l) are not in the cache yet
 		run := func(start, end int) {
-			for j := start; j < end; j++ { // compute rows
+			for j := maxi(start, from); j < end && j < l; j++ { // compute rows
 				rcq[j] = q.kernel.compute(i, j)
 			}
 		}
@@ -125,6 +139,15 @@ func (q *oneClassQ) computeQ(i, j int) float64 {
 	return q.kernel.compute(i, j)
 }
 
+/**
+ * Swaps rows and columns i and j
+ */
+func (q *oneClassQ) swapIndex(i, j int) {
+	q.colCache.swapIndex(i, j)
+	q.kernel.swapIndex(i, j)
+	q.qd[i], q.qd[j] = q.qd[j], q.qd[i]
+}
+
 /**
  * Prints out the cache performance statistics
  */
@@ -143,34 +166,22 @@ func NewOneClassQ(prob *Problem, param *Parameter) *oneClassQ {
 		qd[i] = kernel.compute(i, i)
 	}
 
-	return &oneClassQ{qd: qd, kernel: kernel, parRunner: NewParallelRunner(prob.l), colCache: NewCache(prob.l, prob.l)}
+	return &oneClassQ{qd: qd, kernel: kernel, parRunner: newParallelRunnerLimit(prob.l, param.Parallelism), colCache: NewCache(prob.l, prob.l, param)}
 }
 
 /**
  * Q matrix for support vector regression
  */
 type svrQ struct {
-	l         int       // problem size
-	qd        []float64 // Q matrix diagonial values
-	kernel    kernelFunction
-	parRunner parallelRunner
-	colCache  *cache
-}
-
-func (q *svrQ) real_idx(i int) int {
-	if i < q.l {
-		return i
-	} else {
-		return i - q.l
-	}
-}
-
-func (q *svrQ) sign(i int) float64 {
-	if i < q.l {
-		return 1
-	} else {
-		return -1
-	}
+	l          int       // problem size
+	qd         []float64 // Q matrix diagonial values
+	sign       []float64 // +1 for the alpha half, -1 for the alpha* half of the 2l variables
+	index      []int     // maps each of the 2l variables to its SV in [0,l)
+	kernel     kernelFunction
+	parRunner  parallelRunner
+	colCache   *cache
+	buffer     [2][]float64 // Q columns handed out by getQ(); two are in use at the same time
+	nextBuffer int
 }
 
 /**
@@ -183,34 +194,30 @@ func (q *svrQ) getQD() []float64 {
 /**
  * Get Q values for column i
  */
-func (q *svrQ) getQ(i, l int) []float64 { // @param l is 2 * q.l
-	sign_i := q.sign(i)
-	real_i := q.real_idx(i)
-
-	/*
-		rcq := make([]float64, 2*q.l)
-
-		for j := 0; j < q.l; j++ { // compute rows
-			t := q.kernel.compute(real_i, j)
-			rcq[j] = sign_i * q.sign(j) * t
-			rcq[j+q.l] = sign_i * q.sign(j+l) * t
-		}
-	*/
+func (q *svrQ) getQ(i, l int) []float64 { // @param l is at most 2 * q.l
+	sign_i := q.sign[i]
+	real_i := q.index[i]
 
-	// NOTE: query cache with "real_i" since cache stores [0,l)
-	rcq, newData := q.colCache.getData(real_i)
-	if newData {
+	// NOTE: query cache with "real_i" since cache stores the kernel column for [0,q.l)
+	data, from := q.colCache.getData(real_i, q.l)
+	if from < q.l {
 		run := func(start, end int) {
-			for j := start; j < end; j++ { // compute rows
-				t := q.kernel.compute(real_i, j)
-				rcq[j] = sign_i * q.sign(j) * t
-				rcq[j+q.l] = sign_i * q.sign(j+l) * t
+			for j := maxi(start, from); j < end; j++ { // compute rows
+				data[j] = q.kernel.compute(real_i, j)
 			}
 		}
 
 		q.parRunner.run(run)
 		q.parRunner.waitAll()
 	}
+
+	// The signs of column i depend on which half the variables are in, so the cached
+	// kernel column is expanded into one of two alternating buffers
+	rcq := q.buffer[q.nextBuffer]
+	q.nextBuffer = 1 - q.nextBuffer
+	for j := 0; j < l; j++ {
+		rcq[j] = sign_i * q.sign[j] * data[q.index[j]]
+	}
 	return rcq
 }
 
@@ -218,10 +225,16 @@ func (q *svrQ) getQ(i, l int) []float64 { // @param l is 2 * q.l
  * Computes the Q[i,j] entry
  */
 func (q *svrQ) computeQ(i, j int) float64 {
-	real_i := q.real_idx(i)
-	real_j := q.real_idx(j)
+	return q.sign[i] * q.sign[j] * q.kernel.compute(q.index[i], q.index[j])
+}
 
-	return q.sign(i) * q.sign(j) * q.kernel.compute(real_i, real_j)
+/**
+ * Swaps rows and columns i and j. The kernel cache is indexed by SV and is not affected.
+ */
+func (q *svrQ) swapIndex(i, j int) {
+	q.sign[i], q.sign[j] = q.sign[j], q.sign[i]
+	q.index[i], q.index[j] = q.index[j], q.index[i]
+	q.qd[i], q.qd[j] = q.qd[j], q.qd[i]
 }
 
 /**
@@ -239,10 +252,20 @@ func NewSVRQ(prob *Problem, param *Parameter) *svrQ {
 
 	l := prob.l
 	qd := make([]float64, 2*l)
+	sign := make([]float64, 2*l)
+	index := make([]int, 2*l)
 	for i := 0; i < l; i++ {
 		qd[i] = kernel.compute(i, i)
 		qd[i+l] = qd[i]
+		sign[i] = 1
+		sign[i+l] = -1
+		index[i] = i
+		index[i+l] = i
 	}
 
-	return &svrQ{l: l, qd: qd, kernel: kernel, parRunner: NewParallelRunner(prob.l), colCache: NewCache(prob.l, 2*prob.l)}
+	q := &svrQ{l: l, qd: qd, sign: sign, index: index, kernel: kernel,
+		parRunner: newParallelRunnerLimit(prob.l, param.Parallelism), colCache: NewCache(prob.l, prob.l, param)}
+	q.buffer[0] = make([]float64, 2*l)
+	q.buffer[1] = make([]float64, 2*l)
+	return q
 }
@@ -0,0 +1,82 @@
+package libSvm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSVRQColumns(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var y []float64
+	var X [][]float64
+	for i := 0; i < 20; i++ {
+		X = append(X, []float64{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()})
+		y = append(y, X[i][0]-X[i][2])
+	}
+	prob := denseTestProblem(y, X)
+
+	param := NewParameter()
+	param.SvmType = EPSILON_SVR
+	param.Gamma = 0.5
+	param.QuietMode = true
+
+	q := NewSVRQ(prob, param)
+	var l int = 2 * prob.l
+	sign := func(i int) float64 {
+		if i < prob.l {
+			return 1
+		}
+		return -1
+	}
+	kernel := func(i, j int) float64 {
+		return computeKernelValue(prob.xSpace[prob.x[i%prob.l]:], prob.xSpace[prob.x[j%prob.l]:], param)
+	}
+
+	// the alpha and alpha* variables of an SV share a cached kernel column but have opposite signs,
+	// and the previous column must stay valid while the next one is requested
+	for _, pair := range [][2]int{{3, 3 + prob.l}, {3 + prob.l, 3}, {5, 7 + prob.l}} {
+		qi := q.getQ(pair[0], l)
+		qj := q.getQ(pair[1], l)
+		for k := 0; k < l; k++ {
+			if want := sign(pair[0]) * sign(k) * kernel(pair[0], k); math.Abs(qi[k]-want) > 1e-12 {
+				t.Fatalf("Q[%d][%d] = %g, want %g", pair[0], k, qi[k], want)
+			}
+			if want := sign(pair[1]) * sign(k) * kernel(pair[1], k); math.Abs(qj[k]-want) > 1e-12 {
+				t.Fatalf("Q[%d][%d] = %g, want %g", pair[1], k, qj[k], want)
+			}
+		}
+	}
+}
+
+func TestSVRFit(t *testing.T) {
+	var y []float64
+	var X [][]float64
+	for i := 0; i < 40; i++ {
+		x := float64(i)/20 - 1
+		y = append(y, 2*x+0.5)
+		X = append(X, []float64{x})
+	}
+	prob := denseTestProblem(y, X)
+
+	for _, svmType := range []int{EPSILON_SVR, NU_SVR} {
+		param := NewParameter()
+		param.SvmType = svmType
+		param.KernelType = LINEAR
+		param.C = 100
+		param.P = 0.01
+		param.Nu = 0.2
+		param.QuietMode = true
+
+		model := NewModel(param)
+		if err := model.Train(prob); err != nil {
+			t.Fatal(err)
+		}
+		for i, x := range X {
+			if p := model.Predict(map[int]float64{1: x[0]}); math.Abs(p-y[i]) > 0.05 {
+				t.Errorf("%s: f(%g) = %g, want %g", svm_type_string[svmType], x[0], p, y[i])
+				break
+			}
+		}
+	}
+}
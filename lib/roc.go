@@ -0,0 +1,195 @@
+package libSvm
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+/**
+ * ROCCurve is the receiver operating characteristic of a ranking: the true positive rate against the
+ * false positive rate when every instance scoring at least Thresholds[i] is predicted positive.
+ * The first point is (0, 0) with an infinite threshold.
+ */
+type ROCCurve struct {
+	FPR        []float64
+	TPR        []float64
+	Thresholds []float64 // decreasing
+	AUC        float64   // area under the curve, by the trapezoidal rule
+}
+
+/**
+ * PRCurve is the precision-recall curve of a ranking: the precision and recall when every instance
+ * scoring at least Thresholds[i] is predicted positive. The first point is recall 0, precision 1 with
+ * an infinite threshold.
+ */
+type PRCurve struct {
+	Precision        []float64
+	Recall           []float64
+	Thresholds       []float64 // decreasing
+	AveragePrecision float64   // sum of the precisions weighted by the increase in recall
+}
+
+/**
+ * Computes the ROC curve of scores, e.g. decision values or probability estimates, for the true labels
+ * yTrue. Instances labelled positive are the positive class; a larger score must mean more positive.
+ */
+func NewROCCurve(yTrue, scores []float64, positive float64) (*ROCCurve, error) {
+	points, nrPositive, nrNegative, err := rankScores(yTrue, scores, positive)
+	if err != nil {
+		return nil, err
+	}
+	if nrPositive == 0 || nrNegative == 0 {
+		return nil, fmt.Errorf("Fail to compute ROC curve: need both positive and negative instances\n")
+	}
+
+	c := &ROCCurve{FPR: []float64{0}, TPR: []float64{0}, Thresholds: []float64{math.Inf(1)}}
+	for _, pt := range points {
+		fpr := float64(pt.fp) / float64(nrNegative)
+		tpr := float64(pt.tp) / float64(nrPositive)
+		c.AUC += (fpr - c.FPR[len(c.FPR)-1]) * (tpr + c.TPR[len(c.TPR)-1]) / 2
+
+		c.FPR = append(c.FPR, fpr)
+		c.TPR = append(c.TPR, tpr)
+		c.Thresholds = append(c.Thresholds, pt.threshold)
+	}
+
+	return c, nil
+}
+
+/**
+ * Computes the precision-recall curve of scores for the true labels yTrue, see NewROCCurve.
+ */
+func NewPRCurve(yTrue, scores []float64, positive float64) (*PRCurve, error) {
+	points, nrPositive, _, err := rankScores(yTrue, scores, positive)
+	if err != nil {
+		return nil, err
+	}
+	if nrPositive == 0 {
+		return nil, fmt.Errorf("Fail to compute precision-recall curve: need positive instances\n")
+	}
+
+	c := &PRCurve{Precision: []float64{1}, Recall: []float64{0}, Thresholds: []float64{math.Inf(1)}}
+	for _, pt := range points {
+		precision := float64(pt.tp) / float64(pt.tp+pt.fp)
+		recall := float64(pt.tp) / float64(nrPositive)
+		c.AveragePrecision += (recall - c.Recall[len(c.Recall)-1]) * precision
+
+		c.Precision = append(c.Precision, precision)
+		c.Recall = append(c.Recall, recall)
+		c.Thresholds = append(c.Thresholds, pt.threshold)
+	}
+
+	return c, nil
+}
+
+/**
+ * Returns the area under the ROC curve of scores for the true labels yTrue, see NewROCCurve.
+ */
+func ROCAUC(yTrue, scores []float64, positive float64) (float64, error) {
+	c, err := NewROCCurve(yTrue, scores, positive)
+	if err != nil {
+		return 0, err
+	}
+	return c.AUC, nil
+}
+
+/**
+ * Returns the average precision of scores for the true labels yTrue, see NewPRCurve.
+ */
+func AveragePrecision(yTrue, scores []float64, positive float64) (float64, error) {
+	c, err := NewPRCurve(yTrue, scores, positive)
+	if err != nil {
+		return 0, err
+	}
+	return c.AveragePrecision, nil
+}
+
+/**
+ * Computes the ROC curve of scores for the labels of prob, e.g. of the result of
+ * CrossValidationDecisionValues. If positive is a label of a model and prob was read separately, see
+ * Problem.SetLabelEncoder.
+ */
+func EvaluateROC(prob *Problem, scores []float64, positive float64) (*ROCCurve, error) {
+	return NewROCCurve(prob.y[:prob.l], scores, positive)
+}
+
+/**
+ * Computes the precision-recall curve of scores for the labels of prob, e.g. of the result of
+ * CrossValidationDecisionValues.
+ */
+func EvaluatePR(prob *Problem, scores []float64, positive float64) (*PRCurve, error) {
+	return NewPRCurve(prob.y[:prob.l], scores, positive)
+}
+
+/**
+ * Writes the points of the curve as CSV with the header "threshold,fpr,tpr".
+ */
+func (c *ROCCurve) WriteCSV(w io.Writer) error {
+	return writeCurveCSV(w, []string{"threshold", "fpr", "tpr"}, c.Thresholds, c.FPR, c.TPR)
+}
+
+/**
+ * Writes the points of the curve as CSV with the header "threshold,precision,recall".
+ */
+func (c *PRCurve) WriteCSV(w io.Writer) error {
+	return writeCurveCSV(w, []string{"threshold", "precision", "recall"}, c.Thresholds, c.Precision, c.Recall)
+}
+
+func writeCurveCSV(w io.Writer, header []string, columns ...[]float64) error {
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+
+	record := make([]string, len(columns))
+	for i := range columns[0] {
+		for j, column := range columns {
+			record[j] = strconv.FormatFloat(column[i], 'g', -1, 64)
+		}
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+type rankPoint struct {
+	threshold float64
+	tp        int // positive instances scoring at least threshold
+	fp        int // negative instances scoring at least threshold
+}
+
+/**
+ * Sorts the instances by decreasing score and returns the counts at each distinct score
+ */
+func rankScores(yTrue, scores []float64, positive float64) (points []rankPoint, nrPositive, nrNegative int, err error) {
+	if err = checkPredictions(yTrue, scores); err != nil {
+		return
+	}
+
+	order := make([]int, len(scores))
+	for i := range order {
+		if math.IsNaN(scores[i]) {
+			err = fmt.Errorf("Fail to rank scores: score %d is NaN\n", i)
+			return
+		}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	var tp, fp int = 0, 0
+	for k, i := range order {
+		if yTrue[i] == positive {
+			tp++
+		} else {
+			fp++
+		}
+		if k+1 == len(order) || scores[order[k+1]] != scores[i] { // last instance with this score
+			points = append(points, rankPoint{threshold: scores[i], tp: tp, fp: fp})
+		}
+	}
+
+	return points, tp, fp, nil
+}
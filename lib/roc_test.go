@@ -0,0 +1,117 @@
+package libSvm
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestROCCurve(t *testing.T) {
+	yTrue := []float64{-1, -1, 1, 1}
+	scores := []float64{0.1, 0.4, 0.35, 0.8}
+
+	c, err := NewROCCurve(yTrue, scores, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.FPR, []float64{0, 0, 0.5, 0.5, 1}) || !reflect.DeepEqual(c.TPR, []float64{0, 0.5, 0.5, 1, 1}) {
+		t.Errorf("fpr %v tpr %v", c.FPR, c.TPR)
+	}
+	if !reflect.DeepEqual(c.Thresholds[1:], []float64{0.8, 0.4, 0.35, 0.1}) || !math.IsInf(c.Thresholds[0], 1) {
+		t.Errorf("thresholds %v", c.Thresholds)
+	}
+	if c.AUC != 0.75 {
+		t.Errorf("AUC = %g, want 0.75", c.AUC)
+	}
+
+	ap, err := AveragePrecision(yTrue, scores, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(ap-(0.5*1+0.5*2.0/3)) > 1e-12 {
+		t.Errorf("average precision = %g, want %g", ap, 0.5*1+0.5*2.0/3)
+	}
+
+	// tied scores form a single point, so a constant score gives the diagonal
+	auc, err := ROCAUC([]float64{1, 2, 1, 2}, []float64{3, 3, 3, 3}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auc != 0.5 {
+		t.Errorf("AUC of tied scores = %g, want 0.5", auc)
+	}
+
+	if _, err := NewROCCurve([]float64{1, 1}, []float64{0.2, 0.3}, 1); err == nil {
+		t.Error("expected an error without negative instances")
+	}
+	if _, err := NewPRCurve([]float64{1, 1}, []float64{0.2, math.NaN()}, 1); err == nil {
+		t.Error("expected an error for a NaN score")
+	}
+}
+
+func TestCurveWriteCSV(t *testing.T) {
+	c, err := NewPRCurve([]float64{1, 0, 1}, []float64{2, 1, 0.5}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "threshold,precision,recall\n" +
+		"+Inf,1,0\n" +
+		"2,1,0.5\n" +
+		"1,0.5,0.5\n" +
+		"0.5,0.6666666666666666,1\n"
+	if buf.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestCrossValidationDecisionValues(t *testing.T) {
+	prob := newRandomProblem(80, 30, false, 5)
+
+	param := NewParameter()
+	param.Gamma = 0.01
+	param.QuietMode = true
+
+	for _, probability := range []bool{false, true} {
+		param.Probability = probability
+
+		scores, positive, err := CrossValidationDecisionValues(prob, param, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, label, _, _, _ := groupClasses(prob); positive != label[0] {
+			t.Errorf("positive = %g, want %g", positive, label[0])
+		}
+
+		if !probability { // the sign of the decision values must agree with the predicted labels
+			target := CrossValidation(prob, param, prob.l) // leave-one-out trains the same models
+			loo, _, _ := CrossValidationDecisionValues(prob, param, prob.l)
+			for i := range loo {
+				if math.Abs(loo[i]) > 1e-6 && (loo[i] > 0) != (target[i] == positive) {
+					t.Errorf("instance %d: decision value %g, predicted %g", i, loo[i], target[i])
+				}
+			}
+		}
+
+		c, err := EvaluateROC(prob, scores, positive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.AUC < 0.7 {
+			t.Errorf("probability %v: cross-validated AUC = %g", probability, c.AUC)
+		}
+		if _, err := EvaluatePR(prob, scores, positive); err != nil {
+			t.Error(err)
+		}
+	}
+
+	param.SvmType = EPSILON_SVR
+	if _, _, err := CrossValidationDecisionValues(prob, param, 5); err == nil {
+		t.Error("expected an error for regression")
+	}
+}
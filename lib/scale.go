@@ -0,0 +1,308 @@
+package libSvm
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Scaler linearly rescales features (and optionally labels) to a target range, like LIBSVM's svm-scale.
+ * The per-feature min/max are learned with Fit or restored from a range file with RestoreRange.
+ */
+type Scaler struct {
+	lower, upper   float64   // target range of the features
+	yScaling       bool      // scale the labels as well
+	yLower, yUpper float64   // target range of the labels
+	yMin, yMax     float64   // range of the labels
+	featureMin     []float64 // range of feature i is [featureMin[i], featureMax[i]]
+	featureMax     []float64
+	hasRange       []bool // feature i appeared in the data or range file
+	maxIndex       int
+}
+
+/**
+ * Returns a scaler that maps features to [lower, upper]. Labels are not scaled unless SetYScaling is called.
+ */
+func NewScaler(lower, upper float64) *Scaler {
+	return &Scaler{lower: lower, upper: upper}
+}
+
+/**
+ * Enables scaling of the labels to [lower, upper].
+ */
+func (s *Scaler) SetYScaling(lower, upper float64) {
+	s.yScaling = true
+	s.yLower = lower
+	s.yUpper = upper
+}
+
+func (s *Scaler) grow(maxIndex int) {
+	for len(s.featureMin) <= maxIndex {
+		s.featureMin = append(s.featureMin, math.MaxFloat64)
+		s.featureMax = append(s.featureMax, -math.MaxFloat64)
+		s.hasRange = append(s.hasRange, false)
+	}
+	if maxIndex > s.maxIndex {
+		s.maxIndex = maxIndex
+	}
+}
+
+/**
+ * Learns the min/max of every feature and of the labels over the problem.
+ * Features missing from an instance are implicit zeros and take part in the min/max.
+ */
+func (s *Scaler) Fit(prob *Problem) {
+	s.featureMin = nil
+	s.featureMax = nil
+	s.hasRange = nil
+	s.maxIndex = 0
+	s.yMin = math.MaxFloat64
+	s.yMax = -math.MaxFloat64
+
+	for i := 0; i < prob.l; i++ {
+		for idx := prob.x[i]; prob.xSpace[idx].index != -1; idx++ {
+			s.grow(prob.xSpace[idx].index)
+		}
+	}
+
+	for i := 0; i < prob.l; i++ {
+		s.yMin = minf(s.yMin, prob.y[i])
+		s.yMax = maxf(s.yMax, prob.y[i])
+
+		var nextIndex int = 1
+		for idx := prob.x[i]; prob.xSpace[idx].index != -1; idx++ {
+			index := prob.xSpace[idx].index
+			value := prob.xSpace[idx].value
+			for j := nextIndex; j < index; j++ { // implicit zeros
+				s.updateRange(j, 0)
+			}
+			s.updateRange(index, value)
+			nextIndex = index + 1
+		}
+		for j := nextIndex; j <= s.maxIndex; j++ {
+			s.updateRange(j, 0)
+		}
+	}
+}
+
+func (s *Scaler) updateRange(index int, value float64) {
+	s.featureMin[index] = minf(s.featureMin[index], value)
+	s.featureMax[index] = maxf(s.featureMax[index], value)
+	s.hasRange[index] = true
+}
+
+/**
+ * Returns the scaled value of feature index. The second return value is false if the feature
+ * has no usable range (never seen, or constant), in which case it should be dropped.
+ */
+func (s *Scaler) scaleFeature(index int, value float64) (float64, bool) {
+	if index > s.maxIndex || !s.hasRange[index] || s.featureMin[index] == s.featureMax[index] {
+		return 0, false
+	}
+
+	min, max := s.featureMin[index], s.featureMax[index]
+	if value == min {
+		value = s.lower
+	} else if value == max {
+		value = s.upper
+	} else {
+		value = s.lower + (s.upper-s.lower)*(value-min)/(max-min)
+	}
+	return value, true
+}
+
+/**
+ * Returns the scaled label. The label is unchanged unless y-scaling is enabled.
+ */
+func (s *Scaler) ScaleLabel(y float64) float64 {
+	if !s.yScaling || s.yMin == s.yMax {
+		return y
+	}
+	if y == s.yMin {
+		return s.yLower
+	} else if y == s.yMax {
+		return s.yUpper
+	}
+	return s.yLower + (s.yUpper-s.yLower)*(y-s.yMin)/(s.yMax-s.yMin)
+}
+
+func (s *Scaler) scaleSnode(px []snode) []snode {
+	var out []snode
+
+	var nextIndex int = 1
+	emit := func(index int, value float64) {
+		if v, ok := s.scaleFeature(index, value); ok && v != 0 { // keep zeros implicit
+			out = append(out, snode{index: index, value: v})
+		}
+	}
+
+	for i := 0; px[i].index != -1; i++ {
+		for j := nextIndex; j < px[i].index; j++ {
+			emit(j, 0)
+		}
+		emit(px[i].index, px[i].value)
+		nextIndex = px[i].index + 1
+	}
+	for j := nextIndex; j <= s.maxIndex; j++ {
+		emit(j, 0)
+	}
+
+	return append(out, snode{index: -1})
+}
+
+/**
+ * Returns the scaled version of a single instance.
+ */
+func (s *Scaler) ScaleInstance(x map[int]float64) map[int]float64 {
+	return SnodeToMap(s.scaleSnode(MapToSnode(x)))
+}
+
+/**
+ * Returns a new problem with all the instances (and labels, if y-scaling is enabled) scaled.
+ * String labels (see LabelEncoder) are class names and are never scaled.
+ */
+func (s *Scaler) Transform(prob *Problem) *Problem {
+	scaled := &Problem{l: prob.l, labels: prob.labels, w: prob.w}
+	scaled.y = make([]float64, prob.l)
+	scaled.x = make([]int, prob.l)
+
+	for i := 0; i < prob.l; i++ {
+		scaled.y[i] = prob.y[i]
+		if prob.labels == nil {
+			scaled.y[i] = s.ScaleLabel(prob.y[i])
+		}
+		scaled.x[i] = len(scaled.xSpace)
+		scaled.xSpace = append(scaled.xSpace, s.scaleSnode(prob.xSpace[prob.x[i]:])...)
+	}
+
+	return scaled
+}
+
+/**
+ * Saves the scaling ranges to the specified file in svm-scale's range file format (svm-scale -s).
+ */
+func (s *Scaler) SaveRange(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("Fail to open file %s\n", file)
+	}
+
+	defer f.Close() // close f on method return
+
+	w := bufio.NewWriter(f)
+	if s.yScaling {
+		fmt.Fprintf(w, "y\n")
+		fmt.Fprintf(w, "%.17g %.17g\n", s.yLower, s.yUpper)
+		fmt.Fprintf(w, "%.17g %.17g\n", s.yMin, s.yMax)
+	}
+
+	fmt.Fprintf(w, "x\n")
+	fmt.Fprintf(w, "%.17g %.17g\n", s.lower, s.upper)
+	for i := 1; i <= s.maxIndex; i++ {
+		if s.hasRange[i] && s.featureMin[i] != s.featureMax[i] {
+			fmt.Fprintf(w, "%d %.17g %.17g\n", i, s.featureMin[i], s.featureMax[i])
+		}
+	}
+
+	return w.Flush()
+}
+
+/**
+ * Restores the scaling ranges from the specified file in svm-scale's range file format (svm-scale -r).
+ * The target ranges stored in the file replace the ones the scaler was created with.
+ */
+func (s *Scaler) RestoreRange(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("Fail to open file %s\n", file)
+	}
+
+	defer f.Close() // close f on method return
+
+	s.featureMin = nil
+	s.featureMax = nil
+	s.hasRange = nil
+	s.maxIndex = 0
+	s.yScaling = false
+
+	parsePair := func(line string) (float64, float64, error) {
+		tokens := strings.Fields(line)
+		if len(tokens) != 2 {
+			return 0, 0, fmt.Errorf("Fail to parse range from line %q\n", line)
+		}
+		a, err := strconv.ParseFloat(tokens[0], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Fail to parse range from line %q\n", line)
+		}
+		b, err := strconv.ParseFloat(tokens[1], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Fail to parse range from line %q\n", line)
+		}
+		return a, b, nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return fmt.Errorf("Fail to read range file %s\n", file)
+	}
+
+	if strings.TrimSpace(scanner.Text()) == "y" {
+		s.yScaling = true
+		if !scanner.Scan() {
+			return fmt.Errorf("Fail to read y range from file %s\n", file)
+		}
+		if s.yLower, s.yUpper, err = parsePair(scanner.Text()); err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			return fmt.Errorf("Fail to read y range from file %s\n", file)
+		}
+		if s.yMin, s.yMax, err = parsePair(scanner.Text()); err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			return fmt.Errorf("Fail to read x range from file %s\n", file)
+		}
+	}
+
+	if strings.TrimSpace(scanner.Text()) != "x" {
+		return fmt.Errorf("Fail to read x range from file %s\n", file)
+	}
+	if !scanner.Scan() {
+		return fmt.Errorf("Fail to read x range from file %s\n", file)
+	}
+	if s.lower, s.upper, err = parsePair(scanner.Text()); err != nil {
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+		if len(tokens) != 3 {
+			return fmt.Errorf("Fail to parse feature range from line %q\n", line)
+		}
+
+		index, err := strconv.Atoi(tokens[0])
+		if err != nil || index < 1 {
+			return fmt.Errorf("Fail to parse index from line %q\n", line)
+		}
+		min, max, err := parsePair(tokens[1] + " " + tokens[2])
+		if err != nil {
+			return err
+		}
+
+		s.grow(index)
+		s.featureMin[index] = min
+		s.featureMax[index] = max
+		s.hasRange[index] = true
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,133 @@
+package libSvm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestProblem(y []float64, rows [][]snode) *Problem {
+	prob := &Problem{l: len(y), y: y}
+	for _, row := range rows {
+		prob.x = append(prob.x, len(prob.xSpace))
+		prob.xSpace = append(prob.xSpace, row...)
+		prob.xSpace = append(prob.xSpace, snode{index: -1})
+	}
+	return prob
+}
+
+func TestScalerFitTransform(t *testing.T) {
+	prob := newTestProblem([]float64{1, 2, 3}, [][]snode{
+		{{index: 1, value: 2}, {index: 3, value: 4}},
+		{{index: 1, value: 4}},
+		{{index: 2, value: 5}, {index: 3, value: 2}},
+	})
+
+	scaler := NewScaler(0, 1)
+	scaler.SetYScaling(-1, 1)
+	scaler.Fit(prob)
+
+	scaled := scaler.Transform(prob)
+
+	want := []map[int]float64{
+		{1: 0.5, 3: 1}, // feature 2 is an implicit zero and stays implicit
+		{1: 1},         // feature 3 is 0 which is the min of [0,4]
+		{2: 1, 3: 0.5},
+	}
+	wantY := []float64{-1, 0, 1}
+
+	var i int = 0
+	for scaled.Begin(); !scaled.Done(); scaled.Next() {
+		y, x := scaled.Get()
+		if y != wantY[i] {
+			t.Errorf("instance %d: y = %v, want %v", i, y, wantY[i])
+		}
+		if len(x) != len(want[i]) {
+			t.Errorf("instance %d: x = %v, want %v", i, x, want[i])
+		}
+		for k, v := range want[i] {
+			if x[k] != v {
+				t.Errorf("instance %d: x[%d] = %v, want %v", i, k, x[k], v)
+			}
+		}
+		i++
+	}
+}
+
+func TestScalerSaveRestoreRange(t *testing.T) {
+	prob := newTestProblem([]float64{1, -1}, [][]snode{
+		{{index: 1, value: -3}, {index: 2, value: 7}},
+		{{index: 1, value: 5}, {index: 4, value: 1}},
+	})
+
+	scaler := NewScaler(-1, 1)
+	scaler.SetYScaling(0, 1)
+	scaler.Fit(prob)
+
+	file := filepath.Join(t.TempDir(), "range")
+	if err := scaler.SaveRange(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewScaler(0, 10)
+	if err := restored.RestoreRange(file); err != nil {
+		t.Fatal(err)
+	}
+
+	x := map[int]float64{1: 1, 2: 3.5, 4: 0.5}
+	got := restored.ScaleInstance(x)
+	want := scaler.ScaleInstance(x)
+	if len(got) != len(want) {
+		t.Fatalf("restored scaling = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("restored x[%d] = %v, want %v", k, got[k], v)
+		}
+	}
+	if restored.ScaleLabel(-1) != 0 || restored.ScaleLabel(1) != 1 {
+		t.Errorf("restored y scaling = %v/%v, want 0/1", restored.ScaleLabel(-1), restored.ScaleLabel(1))
+	}
+}
+
+func TestScalerStringLabels(t *testing.T) {
+	prob, err := ReadProblem(strings.NewReader("spam 1:1 2:3\nham 1:3 2:1\nspam 1:2 2:3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scaler := NewScaler(-1, 1)
+	scaler.SetYScaling(-1, 1) // string labels are class names and must not be scaled
+	scaler.Fit(prob)
+
+	var b bytes.Buffer
+	if err := scaler.Transform(prob).WriteLIBSVM(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "spam 1:-1 2:1\nham 1:1 2:-1\nspam 2:1\n"; b.String() != want {
+		t.Errorf("scaled problem %q, want %q", b.String(), want)
+	}
+}
+
+func TestScalerInstanceWeights(t *testing.T) {
+	prob, err := ReadProblem(strings.NewReader("1 w:2 1:1 2:3\n-1 1:3 2:1\n1 w:0.5 1:2 2:3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scaler := NewScaler(-1, 1)
+	scaler.Fit(prob)
+
+	var b bytes.Buffer
+	if err := scaler.Transform(prob).WriteLIBSVM(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 w:2 1:-1 2:1\n-1 w:1 1:1 2:-1\n1 w:0.5 2:1\n"; b.String() != want {
+		t.Errorf("scaled problem %q, want %q", b.String(), want)
+	}
+}
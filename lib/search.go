@@ -0,0 +1,493 @@
+package libSvm
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	PRIOR_UNIFORM     = iota
+	PRIOR_LOG_UNIFORM = iota // uniform in log(value); Low must be > 0
+	PRIOR_INT_UNIFORM = iota // uniform over the integers from Low to High
+)
+
+/**
+ * SearchDimension is a Parameter field searched by RandomSearch or BayesianSearch and the prior
+ * its values are drawn from.
+ */
+type SearchDimension struct {
+	Name  string // "C", "Gamma", "Degree", "Coef0", "Nu" or "P"
+	Low   float64
+	High  float64
+	Prior int
+}
+
+/**
+ * RandomSearch scores parameters drawn at random from the priors of Space by cross validation,
+ * until MaxEvals parameters are scored or MaxTime has passed, whichever comes first.
+ */
+type RandomSearch struct {
+	Param  *Parameter // base parameters; the fields in Space are replaced at each point
+	NrFold int        // number of cross-validation folds
+	Space  []SearchDimension
+
+	MaxEvals int           // maximal number of evaluations; 0 means no limit
+	MaxTime  time.Duration // maximal wall-clock time; 0 means no limit. Evaluations still running are abandoned
+
+	Rand *rand.Rand // source of the random draws; nil means one seeded with Param.Seed
+}
+
+/**
+ * BayesianSearch is a RandomSearch that draws only its first InitialPoints parameters at random.
+ * Each later point maximizes the expected improvement of a Gaussian-process model of the scores so
+ * far among Candidates random draws and perturbations of the best point. Points are scored one at a
+ * time.
+ */
+type BayesianSearch struct {
+	RandomSearch
+	InitialPoints int
+	Candidates    int
+}
+
+/**
+ * SearchPoint is a point of the search space, its values in the order of the dimensions, and its
+ * cross-validation score.
+ */
+type SearchPoint struct {
+	Values []float64
+	Score  float64 // accuracy in percent for classification, mean squared error for regression; NaN if the training failed
+}
+
+/**
+ * SearchResult holds the scored points of a search in the order they were drawn, and the best of them.
+ */
+type SearchResult struct {
+	Names     []string // the names of the dimensions
+	Points    []SearchPoint
+	Best      SearchPoint
+	BestParam *Parameter // Param with the values of Best
+}
+
+/**
+ * Returns the default search space for param: C and gamma log-uniform over grid.py's ranges, degree
+ * from 2 to 5 and coef0 for the polynomial kernel, coef0 for the sigmoid kernel, nu for NU_SVC,
+ * ONE_CLASS and NU_SVR and p log-uniform for EPSILON_SVR. Parameters that param's SVM type or kernel
+ * does not use are left out.
+ */
+func DefaultSearchSpace(param *Parameter) []SearchDimension {
+	var space []SearchDimension
+
+	if param.SvmType != NU_SVC && param.SvmType != ONE_CLASS {
+		space = append(space, SearchDimension{Name: "C", Low: math.Exp2(-5), High: math.Exp2(15), Prior: PRIOR_LOG_UNIFORM})
+	}
+	if param.SvmType == NU_SVC || param.SvmType == ONE_CLASS || param.SvmType == NU_SVR {
+		space = append(space, SearchDimension{Name: "Nu", Low: 0.05, High: 0.95, Prior: PRIOR_UNIFORM})
+	}
+	if param.SvmType == EPSILON_SVR {
+		space = append(space, SearchDimension{Name: "P", Low: math.Exp2(-8), High: math.Exp2(-1), Prior: PRIOR_LOG_UNIFORM})
+	}
+
+	switch param.KernelType {
+	case POLY:
+		space = append(space,
+			SearchDimension{Name: "Gamma", Low: math.Exp2(-15), High: math.Exp2(3), Prior: PRIOR_LOG_UNIFORM},
+			SearchDimension{Name: "Degree", Low: 2, High: 5, Prior: PRIOR_INT_UNIFORM},
+			SearchDimension{Name: "Coef0", Low: 0, High: 1, Prior: PRIOR_UNIFORM})
+	case RBF:
+		space = append(space,
+			SearchDimension{Name: "Gamma", Low: math.Exp2(-15), High: math.Exp2(3), Prior: PRIOR_LOG_UNIFORM})
+	case SIGMOID:
+		space = append(space,
+			SearchDimension{Name: "Gamma", Low: math.Exp2(-15), High: math.Exp2(3), Prior: PRIOR_LOG_UNIFORM},
+			SearchDimension{Name: "Coef0", Low: -1, High: 1, Prior: PRIOR_UNIFORM})
+	}
+
+	return space
+}
+
+/**
+ * Returns a random search over DefaultSearchSpace(param) with 5-fold cross validation and a budget
+ * of 60 evaluations.
+ */
+func NewRandomSearch(param *Parameter) *RandomSearch {
+	return &RandomSearch{Param: param, NrFold: 5, Space: DefaultSearchSpace(param), MaxEvals: 60}
+}
+
+/**
+ * Returns a Bayesian search over DefaultSearchSpace(param) with 5-fold cross validation, a budget
+ * of 40 evaluations, 10 of them random, and 1000 candidates per step.
+ */
+func NewBayesianSearch(param *Parameter) *BayesianSearch {
+	s := &BayesianSearch{RandomSearch: *NewRandomSearch(param), InitialPoints: 10, Candidates: 1000}
+	s.MaxEvals = 40
+	return s
+}
+
+/**
+ * Maps u in [0,1] to a value of the dimension, linearly in the scale of the prior
+ */
+func (d *SearchDimension) fromUnit(u float64) float64 {
+	u = maxf(0, minf(1, u))
+	switch d.Prior {
+	case PRIOR_LOG_UNIFORM:
+		return math.Exp(math.Log(d.Low) + u*(math.Log(d.High)-math.Log(d.Low)))
+	case PRIOR_INT_UNIFORM: // each integer gets an equal share of [0,1]
+		return minf(d.High, math.Floor(d.Low+u*(d.High-d.Low+1)))
+	}
+	return d.Low + u*(d.High-d.Low)
+}
+
+func (d *SearchDimension) check() error {
+	switch d.Name {
+	case "C", "Gamma", "Degree", "Coef0", "Nu", "P":
+	default:
+		return fmt.Errorf("Fail to search: unknown parameter %q\n", d.Name)
+	}
+	if d.Low > d.High || (d.Prior == PRIOR_LOG_UNIFORM && d.Low <= 0) {
+		return fmt.Errorf("Fail to search: invalid range [%g, %g] for %s\n", d.Low, d.High, d.Name)
+	}
+	return nil
+}
+
+/**
+ * Returns a copy of param with the values of the dimensions of space
+ */
+func searchParameter(param *Parameter, space []SearchDimension, values []float64) *Parameter {
+	pointParam := *param
+	for i, d := range space {
+		switch d.Name {
+		case "C":
+			pointParam.C = values[i]
+		case "Gamma":
+			pointParam.Gamma = values[i]
+		case "Degree":
+			pointParam.Degree = int(math.Round(values[i]))
+		case "Coef0":
+			pointParam.Coef0 = values[i]
+		case "Nu":
+			pointParam.Nu = values[i]
+		case "P":
+			pointParam.P = values[i]
+		}
+	}
+	return &pointParam
+}
+
+/**
+ * Checks the search settings and returns the random source and a context that expires with MaxTime
+ */
+func (s *RandomSearch) start(ctx context.Context) (context.Context, context.CancelFunc, *rand.Rand, error) {
+	if s.NrFold < 2 {
+		return nil, nil, nil, fmt.Errorf("Fail to search: number of folds %d must be >= 2\n", s.NrFold)
+	}
+	if s.MaxEvals <= 0 && s.MaxTime <= 0 {
+		return nil, nil, nil, fmt.Errorf("Fail to search: need a budget, MaxEvals or MaxTime\n")
+	}
+	if len(s.Space) == 0 {
+		return nil, nil, nil, fmt.Errorf("Fail to search: empty search space\n")
+	}
+	for i := range s.Space {
+		if err := s.Space[i].check(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	rng := s.Rand
+	if rng == nil {
+		rng = s.Param.newRand()
+	}
+
+	if s.MaxTime > 0 {
+		budgetCtx, cancel := context.WithTimeout(ctx, s.MaxTime)
+		return budgetCtx, cancel, rng, nil
+	}
+	budgetCtx, cancel := context.WithCancel(ctx)
+	return budgetCtx, cancel, rng, nil
+}
+
+/**
+ * Draws a point from the priors, as unit coordinates
+ */
+func (s *RandomSearch) sample(rng *rand.Rand) []float64 {
+	u := make([]float64, len(s.Space))
+	for i := range u {
+		u[i] = rng.Float64()
+	}
+	return u
+}
+
+func (s *RandomSearch) values(u []float64) []float64 {
+	values := make([]float64, len(u))
+	for i := range u {
+		values[i] = s.Space[i].fromUnit(u[i])
+	}
+	return values
+}
+
+/**
+ * Scores the point with the base parameters param; ok is false if the budget ran out during the evaluation
+ */
+func (s *RandomSearch) evaluate(ctx context.Context, prob *Problem, param *Parameter, values []float64) (point SearchPoint, ok bool, err error) {
+	point = SearchPoint{Values: values, Score: math.NaN()}
+	point.Score, err = crossValidationScore(ctx, prob, searchParameter(param, s.Space, values), s.NrFold)
+	if err != nil {
+		point.Score = math.NaN()
+		return point, ctx.Err() == nil, err
+	}
+	return point, true, nil
+}
+
+/**
+ * Sets the best point of the result. Returns the error to report: ctx's if it was cancelled (not by
+ * the time budget), firstErr if no point could be scored.
+ */
+func (s *RandomSearch) finish(ctx context.Context, result *SearchResult, firstErr error) error {
+	regression := s.Param.SvmType == EPSILON_SVR || s.Param.SvmType == NU_SVR
+
+	var found bool = false
+	for _, p := range result.Points {
+		if math.IsNaN(p.Score) {
+			continue
+		}
+		better := p.Score > result.Best.Score
+		if regression {
+			better = p.Score < result.Best.Score
+		}
+		if !found || better {
+			result.Best = p
+			found = true
+		}
+	}
+	if found {
+		result.BestParam = searchParameter(s.Param, s.Space, result.Best.Values)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !found {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("Fail to search: no point was scored within the time budget\n")
+		}
+		return firstErr
+	}
+	return nil
+}
+
+func (s *RandomSearch) newResult() *SearchResult {
+	result := &SearchResult{}
+	for _, d := range s.Space {
+		result.Names = append(result.Names, d.Name)
+	}
+	return result
+}
+
+/**
+ * Same as RunContext with a context that is never cancelled.
+ */
+func (s *RandomSearch) Run(prob *Problem) (*SearchResult, error) {
+	return s.RunContext(context.Background(), prob)
+}
+
+/**
+ * Scores random parameters by cross validation on prob until the budget is spent, using at most
+ * Param.Parallelism concurrent cross validations (one per CPU if 0), and returns the scores and the
+ * best point. As for GridSearch, a point whose training fails scores NaN, and an error is returned
+ * only if no point could be scored or ctx is cancelled. The concurrent cross validations train on a
+ * single goroutine each and their calls to Param.Logger and Param.Progress are serialised. The points
+ * are returned in the order they were drawn, so that a search with MaxEvals and a seeded Rand (and
+ * Param.Seed for the folds) is reproducible.
+ */
+func (s *RandomSearch) RunContext(ctx context.Context, prob *Problem) (*SearchResult, error) {
+	budgetCtx, cancel, rng, err := s.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	result := s.newResult()
+
+	workers := s.Param.Parallelism
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	workerParam := concurrentParameter(s.Param, workers)
+
+	type draw struct {
+		n      int // index of the draw
+		values []float64
+		point  SearchPoint
+	}
+	var scored []draw
+
+	var mu sync.Mutex // guards scored and firstErr
+	var firstErr error
+	var wg sync.WaitGroup
+	jobs := make(chan draw)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				point, ok, err := s.evaluate(budgetCtx, prob, workerParam, job.values)
+				if !ok {
+					continue
+				}
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				job.point = point
+				scored = append(scored, job)
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for n := 0; s.MaxEvals <= 0 || n < s.MaxEvals; n++ {
+		select {
+		case jobs <- draw{n: n, values: s.values(s.sample(rng))}:
+		case <-budgetCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(scored, func(a, b int) bool { return scored[a].n < scored[b].n })
+	for _, d := range scored {
+		result.Points = append(result.Points, d.point)
+	}
+
+	return result, s.finish(ctx, result, firstErr)
+}
+
+/**
+ * Same as RunContext with a context that is never cancelled.
+ */
+func (s *BayesianSearch) Run(prob *Problem) (*SearchResult, error) {
+	return s.RunContext(context.Background(), prob)
+}
+
+/**
+ * Scores parameters chosen by Bayesian optimization by cross validation on prob until the budget is
+ * spent, and returns the scores and the best point. Errors are handled as by RandomSearch.
+ */
+func (s *BayesianSearch) RunContext(ctx context.Context, prob *Problem) (*SearchResult, error) {
+	budgetCtx, cancel, rng, err := s.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	result := s.newResult()
+	regression := s.Param.SvmType == EPSILON_SVR || s.Param.SvmType == NU_SVR
+
+	var firstErr error
+	var observed [][]float64 // unit coordinates of the scored points
+	for n := 0; (s.MaxEvals <= 0 || n < s.MaxEvals) && budgetCtx.Err() == nil; n++ {
+		var u []float64
+		if n < s.InitialPoints || len(observed) == 0 {
+			u = s.sample(rng)
+		} else {
+			u = s.suggest(rng, observed, result.Points, regression)
+		}
+
+		point, ok, err := s.evaluate(budgetCtx, prob, s.Param, s.values(u))
+		if !ok {
+			break
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		result.Points = append(result.Points, point)
+		observed = append(observed, u)
+	}
+
+	return result, s.finish(ctx, result, firstErr)
+}
+
+/**
+ * Returns the candidate with the largest expected improvement under a Gaussian process fitted to the
+ * scored points. Failed points count as the worst score seen so far.
+ */
+func (s *BayesianSearch) suggest(rng *rand.Rand, observed [][]float64, points []SearchPoint, regression bool) []float64 {
+	y := make([]float64, len(points))
+	var worst float64 = math.Inf(1)
+	var best int = -1
+	for i, p := range points {
+		y[i] = p.Score
+		if regression {
+			y[i] = -p.Score // maximize
+		}
+		if !math.IsNaN(y[i]) {
+			worst = minf(worst, y[i])
+			if best < 0 || y[i] > y[best] {
+				best = i
+			}
+		}
+	}
+	if best < 0 { // nothing scored yet
+		return s.sample(rng)
+	}
+	for i := range y {
+		if math.IsNaN(y[i]) {
+			y[i] = worst
+		}
+	}
+
+	gp := fitGaussianProcess(observed, y)
+
+	var bestCandidate []float64
+	var bestImprovement float64 = math.Inf(-1)
+	for c := 0; c < maxi(s.Candidates, 1); c++ {
+		var u []float64
+		if c%10 == 9 { // a local step around the best point
+			u = make([]float64, len(s.Space))
+			for k := range u {
+				u[k] = maxf(0, minf(1, observed[best][k]+0.05*rng.NormFloat64()))
+			}
+		} else {
+			u = s.sample(rng)
+		}
+
+		if ei := gp.expectedImprovement(u, y[best]); ei > bestImprovement {
+			bestCandidate, bestImprovement = u, ei
+		}
+	}
+
+	return bestCandidate
+}
+
+/**
+ * Writes the scored points as CSV, one row per point in the order they were scored, with a column for
+ * each dimension followed by "score".
+ */
+func (r *SearchResult) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write(append(append([]string{}, r.Names...), "score"))
+
+	record := make([]string, len(r.Names)+1)
+	for _, p := range r.Points {
+		for i, v := range p.Values {
+			record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		record[len(r.Names)] = strconv.FormatFloat(p.Score, 'g', -1, 64)
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,185 @@
+package libSvm
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSearchDimension(t *testing.T) {
+	d := SearchDimension{Name: "C", Low: 0.125, High: 8, Prior: PRIOR_LOG_UNIFORM}
+	if v := d.fromUnit(0.5); math.Abs(v-1) > 1e-12 {
+		t.Errorf("log-uniform midpoint = %g, want 1", v)
+	}
+
+	d = SearchDimension{Name: "Degree", Low: 2, High: 5, Prior: PRIOR_INT_UNIFORM}
+	counts := make(map[float64]int)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 4000; i++ {
+		counts[d.fromUnit(rng.Float64())]++
+	}
+	if len(counts) != 4 || counts[2] < 900 || counts[5] < 900 {
+		t.Errorf("integer draws = %v, want about 1000 each of 2..5", counts)
+	}
+}
+
+func TestGaussianProcess(t *testing.T) {
+	var x [][]float64
+	var y []float64
+	for i := 0; i <= 10; i++ {
+		x = append(x, []float64{float64(i) / 10})
+		y = append(y, math.Sin(6*float64(i)/10))
+	}
+
+	gp := fitGaussianProcess(x, y)
+	for _, v := range []float64{0.25, 0.55, 0.85} {
+		mu, sigma := gp.predict([]float64{v})
+		if math.Abs(mu-math.Sin(6*v)) > 0.05 || sigma > 0.2 {
+			t.Errorf("predict(%g) = %g +- %g, want %g", v, mu, sigma, math.Sin(6*v))
+		}
+	}
+
+	// the improvement is expected near the maximum at pi/12, not at the minimum
+	if near, far := gp.expectedImprovement([]float64{math.Pi / 12}, 1), gp.expectedImprovement([]float64{0.78}, 1); near <= far {
+		t.Errorf("expected improvement %g near the maximum, %g near the minimum", near, far)
+	}
+}
+
+func TestRandomSearch(t *testing.T) {
+	prob := newRandomProblem(60, 4, false, 3)
+
+	param := NewParameter()
+	param.KernelType = POLY
+	param.QuietMode = true
+
+	s := NewRandomSearch(param)
+	if len(s.Space) != 4 {
+		t.Fatalf("default space for POLY = %+v", s.Space)
+	}
+	s.Space[0].High = 8 // keep the solver fast
+	s.Space[1].High = 1
+	s.MaxEvals = 6
+	s.Rand = rand.New(rand.NewSource(2))
+
+	result, err := s.Run(prob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != 6 || strings.Join(result.Names, ",") != "C,Gamma,Degree,Coef0" {
+		t.Fatalf("%d points of %v", len(result.Points), result.Names)
+	}
+	for _, p := range result.Points {
+		for i, d := range s.Space {
+			if p.Values[i] < d.Low || p.Values[i] > d.High {
+				t.Errorf("%s = %g is outside [%g, %g]", d.Name, p.Values[i], d.Low, d.High)
+			}
+		}
+		if p.Values[2] != math.Floor(p.Values[2]) {
+			t.Errorf("degree %g is not an integer", p.Values[2])
+		}
+		if p.Score > result.Best.Score {
+			t.Errorf("point %+v is better than best %+v", p, result.Best)
+		}
+	}
+	if result.BestParam.C != result.Best.Values[0] || float64(result.BestParam.Degree) != result.Best.Values[2] {
+		t.Errorf("best parameters C=%g degree=%d for %v", result.BestParam.C, result.BestParam.Degree, result.Best.Values)
+	}
+
+	var buf bytes.Buffer
+	if err := result.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 7 || lines[0] != "C,Gamma,Degree,Coef0,score" {
+		t.Errorf("csv =\n%s", buf.String())
+	}
+
+	// a time budget alone stops the search
+	s.MaxEvals = 0
+	s.MaxTime = 200 * time.Millisecond
+	start := time.Now()
+	if result, err = s.Run(prob); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second || len(result.Points) == 0 {
+		t.Errorf("time budget: %d points in %v", len(result.Points), elapsed)
+	}
+
+	// the points are in draw order, so a seeded search is reproducible however the evaluations interleave
+	var active int32
+	param.Seed = 1
+	param.Parallelism = 4
+	param.QuietMode = false
+	param.Logger = func(string) {
+		if atomic.AddInt32(&active, 1) > 1 {
+			t.Error("Logger called concurrently")
+		}
+		time.Sleep(100 * time.Microsecond) // give another call the time to overlap
+		atomic.AddInt32(&active, -1)
+	}
+	s.MaxTime = 0
+	s.MaxEvals = 8
+	var runs [2]*SearchResult
+	for k := range runs {
+		s.Rand = rand.New(rand.NewSource(2))
+		if runs[k], err = s.Run(prob); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(runs[0].Points, runs[1].Points) {
+		t.Errorf("points differ with the same seeds:\n%+v\n%+v", runs[0].Points, runs[1].Points)
+	}
+	s.Rand = rand.New(rand.NewSource(2))
+	if first := s.sample(s.Rand); s.values(first)[0] != runs[0].Points[0].Values[0] {
+		t.Errorf("first point %v is not the first draw", runs[0].Points[0].Values)
+	}
+
+	s.MaxEvals = 0
+	if _, err := s.Run(prob); err == nil {
+		t.Error("expected an error without a budget")
+	}
+	s.MaxEvals = 1
+	s.Space = []SearchDimension{{Name: "Cost", Low: 1, High: 2}}
+	if _, err := s.Run(prob); err == nil {
+		t.Error("expected an error for an unknown parameter")
+	}
+}
+
+func TestBayesianSearch(t *testing.T) {
+	prob := newRandomProblem(60, 3, true, 4)
+
+	param := NewParameter()
+	param.SvmType = EPSILON_SVR
+	param.KernelType = LINEAR
+	param.QuietMode = true
+
+	s := NewBayesianSearch(param)
+	s.Space = []SearchDimension{{Name: "C", Low: 1.0 / 256, High: 4, Prior: PRIOR_LOG_UNIFORM}}
+	s.MaxEvals = 12
+	s.InitialPoints = 4
+	s.Candidates = 200
+	s.Rand = rand.New(rand.NewSource(5))
+	param.Seed = 1 // the same folds at each run
+
+	result, err := s.Run(prob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != 12 {
+		t.Fatalf("%d points, want 12", len(result.Points))
+	}
+
+	// the data is nearly linear, so a large C fits best; the surrogate must find one
+	if result.Best.Values[0] < 0.25 {
+		t.Errorf("best C = %g, MSE %g", result.Best.Values[0], result.Best.Score)
+	}
+	for _, p := range result.Points {
+		if p.Score < result.Best.Score {
+			t.Errorf("point %+v has a smaller MSE than best %+v", p, result.Best)
+		}
+	}
+}
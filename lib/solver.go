@@ -1,7 +1,7 @@
-package main
+package libSvm
 
 import (
-	"fmt"
+	"context"
 	"math"
 )
 
@@ -11,6 +11,16 @@ const (
 	FREE        = iota
 )
 
+/**
+ * SolverProgress is the state of the SMO solver reported to Parameter.Progress
+ */
+type SolverProgress struct {
+	Iter     int     // number of iterations done
+	Gap      float64 // maximal violation gmax+gmax2 of the last working set selection; the solver stops when it is below Eps
+	Obj      float64 // estimate of the objective value (the gradient of shrunk variables may be stale)
+	NrFreeSV int     // number of free support vectors, 0 < alpha_i < C_i
+}
+
 type Solver struct {
 	l            int     // problem size
 	q            matrixQ // Q matrix
@@ -19,12 +29,18 @@ type Solver struct {
 	alpha        []float64
 	alpha_status []int8
 	qd           []float64 // Q matrix diagonial values
-	penaltyCp    float64
-	penaltyCn    float64
-	y            []int8 // class, +1 or -1
+	C            []float64 // upper bound of each variable
+	y            []int8    // class, +1 or -1
 	eps          float64
+	param        *Parameter
 	workingSet   workingSetSelecter
 	parRunner    parallelRunner
+	activeSize   int       // variables [0,activeSize) are in the active set; the rest are shrunk
+	activeSet    []int     // original index of each (possibly swapped) variable
+	gBar         []float64 // gradient contribution of the variables at the upper bound
+	shrinking    bool      // use the shrinking heuristics
+	unshrink     bool      // the full gradient has been reconstructed once near convergence
+	gap          float64   // maximal violation found by the last working set selection
 }
 
 func (solver Solver) isUpperBound(i int) bool {
@@ -44,11 +60,11 @@ func (solver Solver) isLowerBound(i int) bool {
 }
 
 func (solver Solver) getC(i int) float64 {
-	if solver.y[i] > 0 {
-		return solver.penaltyCp
-	} else {
-		return solver.penaltyCn
-	}
+	return solver.C[i]
+}
+
+func (solver Solver) isFree(i int) bool {
+	return solver.alpha_status[i] == FREE
 }
 
 func (solver *Solver) updateAlphaStatus(i int) {
@@ -61,51 +77,93 @@ func (solver *Solver) updateAlphaStatus(i int) {
 	}
 }
 
-func (solver *Solver) Solve() solution {
+/**
+ * Runs the SMO algorithm. Returns ctx.Err() if the context is done before the solver converged.
+ */
+func (solver *Solver) Solve(ctx context.Context) (solution, error) {
 
 	solver.alpha_status = make([]int8, solver.l)
 	for i := 0; i < solver.l; i++ {
 		solver.updateAlphaStatus(i)
 	}
 
+	// Initialize active set (for shrinking)
+	solver.activeSize = solver.l
+	solver.activeSet = make([]int, solver.l)
+	for i := 0; i < solver.l; i++ {
+		solver.activeSet[i] = i
+	}
+	solver.unshrink = false
+
 	// Initialize gradient
 	solver.gradient = make([]float64, solver.l)
+	solver.gBar = make([]float64, solver.l)
 	for i := 0; i < solver.l; i++ {
 		solver.gradient[i] = solver.p[i]
 	}
 
 	for i := 0; i < solver.l; i++ {
+		if solver.isLowerBound(i) {
+			continue // alpha_i is 0 and does not contribute
+		}
 		var alpha_i float64 = solver.alpha[i]
 		Q_i := solver.q.getQ(i, solver.l) // getQ() is parallelized in the respective matrixQ implementation
 		// solver.initGradientInnerLoop(Q_i, alpha_i) // no improvement
 		for j := 0; j < solver.l; j++ {
 			solver.gradient[j] += alpha_i * Q_i[j]
 		}
+		if solver.isUpperBound(i) {
+			C_i := solver.getC(i)
+			for j := 0; j < solver.l; j++ {
+				solver.gBar[j] += C_i * Q_i[j]
+			}
+		}
 	}
 	// solver.initGradient() // Alternative parallelization strategy - no improvement
 
 	var iter int = 0
 	var max_iter int = 0
-	if solver.l > math.MaxInt32/100 {
-		max_iter = math.MaxInt32
+	if solver.param.MaxIter > 0 {
+		max_iter = solver.param.MaxIter
 	} else {
-		max_iter = 100 * solver.l
+		if solver.l > math.MaxInt32/100 {
+			max_iter = math.MaxInt32
+		} else {
+			max_iter = 100 * solver.l
+		}
+		max_iter = maxi(10000000, max_iter)
 	}
-	max_iter = maxi(10000000, max_iter)
 	var counter = mini(solver.l, 1000) + 1
+	var stopped bool = false // stopped early by Parameter.Progress
 
 	for iter < max_iter {
 		if counter = counter - 1; counter == 0 {
 			counter = mini(solver.l, 1000)
-			fmt.Print(".")
+			if err := ctx.Err(); err != nil {
+				return solution{}, err
+			}
+			if solver.param.Progress != nil && iter > 0 && !solver.param.Progress(solver.progress(iter)) {
+				stopped = true
+				break
+			}
+			if solver.shrinking {
+				solver.workingSet.doShrinking(solver)
+			}
+			solver.param.info(".")
 		}
 
 		var i int = 0
 		var j int = 0
 		var rc int = 0
 		if i, j, rc = solver.workingSet.workingSetSelect(solver); rc != 0 {
-			fmt.Print("*")
-			break
+			// reconstruct the whole gradient and check again before stopping
+			solver.reconstructGradient()
+			solver.activeSize = solver.l
+			solver.param.info("*")
+			if i, j, rc = solver.workingSet.workingSetSelect(solver); rc != 0 {
+				break
+			}
+			counter = 1 // do shrinking next iteration
 		}
 
 		iter++
@@ -116,8 +174,8 @@ func (solver *Solver) Solve() solution {
 		oldAlpha_i := solver.alpha[i]
 		oldAlpha_j := solver.alpha[j]
 
-		Q_i := solver.q.getQ(i, solver.l) // column i of Q matrix
-		Q_j := solver.q.getQ(j, solver.l) // column j of Q matrix
+		Q_i := solver.q.getQ(i, solver.activeSize) // column i of Q matrix
+		Q_j := solver.q.getQ(j, solver.activeSize) // column j of Q matrix
 
 		if solver.y[i] != solver.y[j] {
 
@@ -196,12 +254,32 @@ func (solver *Solver) Solve() solution {
 		deltaAlpha_j := solver.alpha[j] - oldAlpha_j
 		solver.updateGradient(Q_i, Q_j, deltaAlpha_i, deltaAlpha_j)
 
+		ui := solver.isUpperBound(i)
+		uj := solver.isUpperBound(j)
 		solver.updateAlphaStatus(i)
 		solver.updateAlphaStatus(j)
+		solver.updateGBar(i, ui)
+		solver.updateGBar(j, uj)
+	}
+
+	if iter >= max_iter || stopped {
+		if solver.activeSize < solver.l {
+			// reconstruct the whole gradient to calculate objective value
+			solver.reconstructGradient()
+			solver.activeSize = solver.l
+			solver.param.info("*")
+		}
+		if stopped {
+			solver.param.info("\nWARNING: stopped by the progress callback\n")
+		} else {
+			solver.param.info("\nWARNING: reaching max number of iterations\n")
+		}
 	}
 
 	var si solution
 
+	si.maxIterReached = iter >= max_iter
+	si.stopped = stopped
 	si.rho, si.r = solver.workingSet.calculateRho(solver)
 
 	var v float64 = 0 // calculate objective value
@@ -210,15 +288,132 @@ func (solver *Solver) Solve() solution {
 	}
 	si.obj = v / 2
 
-	si.upper_bound_p = solver.penaltyCp
-	si.upper_bound_n = solver.penaltyCn
+	// put back the solution in the original order of the variables
+	si.alpha = make([]float64, solver.l)
+	si.upper_bound = make([]float64, solver.l)
+	for i := 0; i < solver.l; i++ {
+		si.alpha[solver.activeSet[i]] = solver.alpha[i]
+		si.upper_bound[solver.activeSet[i]] = solver.C[i]
+	}
+
+	solver.param.info("\noptimization finished, #iter = %d\n", iter)
+	solver.q.showCacheStats() // show cache statistics
+
+	return si, nil
+}
+
+/**
+ * Returns the current state of the solver for Parameter.Progress
+ */
+func (solver *Solver) progress(iter int) SolverProgress {
+	var v float64 = 0
+	var nrFree int = 0
+	for i := 0; i < solver.l; i++ {
+		v += solver.alpha[i] * (solver.gradient[i] + solver.p[i])
+		if solver.isFree(i) {
+			nrFree++
+		}
+	}
+	return SolverProgress{Iter: iter, Gap: solver.gap, Obj: v / 2, NrFreeSV: nrFree}
+}
+
+/**
+ * Keeps gBar up to date when alpha_i enters or leaves the upper bound
+ */
+func (solver *Solver) updateGBar(i int, wasUpperBound bool) {
+	if wasUpperBound == solver.isUpperBound(i) {
+		return
+	}
 
-	si.alpha = solver.alpha
+	C_i := solver.getC(i)
+	Q_i := solver.q.getQ(i, solver.l)
+	if wasUpperBound {
+		for k := 0; k < solver.l; k++ {
+			solver.gBar[k] -= C_i * Q_i[k]
+		}
+	} else {
+		for k := 0; k < solver.l; k++ {
+			solver.gBar[k] += C_i * Q_i[k]
+		}
+	}
+}
 
-	fmt.Printf("\noptimization finished, #iter = %d\n", iter)
-	// solver.q.showCacheStats() // show cache statistics
+/**
+ * Reconstructs the gradient of the shrunk variables from gBar and the free variables
+ */
+func (solver *Solver) reconstructGradient() {
+	if solver.activeSize == solver.l {
+		return
+	}
 
-	return si
+	for j := solver.activeSize; j < solver.l; j++ {
+		solver.gradient[j] = solver.gBar[j] + solver.p[j]
+	}
+
+	var nrFree int = 0
+	for j := 0; j < solver.activeSize; j++ {
+		if solver.isFree(j) {
+			nrFree++
+		}
+	}
+
+	if 2*nrFree < solver.activeSize {
+		solver.param.info("\nWARNING: using -h 0 may be faster\n")
+	}
+
+	if nrFree*solver.l > 2*solver.activeSize*(solver.l-solver.activeSize) {
+		for i := solver.activeSize; i < solver.l; i++ {
+			Q_i := solver.q.getQ(i, solver.activeSize)
+			for j := 0; j < solver.activeSize; j++ {
+				if solver.isFree(j) {
+					solver.gradient[i] += solver.alpha[j] * Q_i[j]
+				}
+			}
+		}
+	} else {
+		for i := 0; i < solver.activeSize; i++ {
+			if solver.isFree(i) {
+				Q_i := solver.q.getQ(i, solver.l)
+				alpha_i := solver.alpha[i]
+				for j := solver.activeSize; j < solver.l; j++ {
+					solver.gradient[j] += alpha_i * Q_i[j]
+				}
+			}
+		}
+	}
+}
+
+/**
+ * Swaps variables i and j in the solver and the Q matrix
+ */
+func (solver *Solver) swapIndex(i, j int) {
+	solver.q.swapIndex(i, j) // also swaps solver.qd since it is shared with the Q matrix
+	solver.y[i], solver.y[j] = solver.y[j], solver.y[i]
+	solver.gradient[i], solver.gradient[j] = solver.gradient[j], solver.gradient[i]
+	solver.alpha_status[i], solver.alpha_status[j] = solver.alpha_status[j], solver.alpha_status[i]
+	solver.alpha[i], solver.alpha[j] = solver.alpha[j], solver.alpha[i]
+	solver.p[i], solver.p[j] = solver.p[j], solver.p[i]
+	solver.C[i], solver.C[j] = solver.C[j], solver.C[i]
+	solver.activeSet[i], solver.activeSet[j] = solver.activeSet[j], solver.activeSet[i]
+	solver.gBar[i], solver.gBar[j] = solver.gBar[j], solver.gBar[i]
+}
+
+/**
+ * Removes the variables that are unlikely to change from the active set. beShrunk decides per variable.
+ */
+func (solver *Solver) shrink(beShrunk func(i int) bool) {
+	for i := 0; i < solver.activeSize; i++ {
+		if beShrunk(i) {
+			solver.activeSize--
+			for solver.activeSize > i {
+				if !beShrunk(solver.activeSize) {
+					solver.swapIndex(i, solver.activeSize)
+					break
+				}
+				solver.activeSize--
+			}
+		}
+	}
 }
 
 func (solver *Solver) initGradientInnerLoop(Q_i []float64, alpha_i float64) {
@@ -233,7 +428,7 @@ func (solver *Solver) initGradientInnerLoop(Q_i []float64, alpha_i float64) {
 	// We CANNOT use solver.parRunner here because that has been initialized with prob.l
 	// solver.l == 2 * prob.l in the case for SVRQ
 	// Therefore we create this just once in the initialization phase of the Solve() method
-	runner := NewParallelRunner(solver.l)
+	runner := newParallelRunnerLimit(solver.l, solver.param.Parallelism)
 	runner.run(run)
 	runner.waitAll()
 }
@@ -251,15 +446,16 @@ func (solver *Solver) initGradient() {
 	// We CANNOT use solver.parRunner here because that has been initialized with prob.l
 	// solver.l == 2 * prob.l in the case for SVRQ
 	// Therefore we create this just once in the initialization phase of the Solve() method
-	runner := NewParallelRunner(solver.l)
+	runner := newParallelRunnerLimit(solver.l, solver.param.Parallelism)
 	runner.run(run)
 	runner.waitAll()
 }
 
 func (solver *Solver) updateGradient(Q_i, Q_j []float64, deltaAlpha_i, deltaAlpha_j float64) {
 
+	activeSize := solver.activeSize
 	run := func(start, end int) {
-		for k := start; k < end; k++ {
+		for k := start; k < end && k < activeSize; k++ {
 			t := Q_i[k]*deltaAlpha_i + Q_j[k]*deltaAlpha_j
 			solver.gradient[k] += t
 		}
@@ -269,17 +465,25 @@ func (solver *Solver) updateGradient(Q_i, Q_j []float64, deltaAlpha_i, deltaAlph
 	solver.parRunner.waitAll() // wait for all the parallel runs to complete
 }
 
-func NewSolver(l int, q matrixQ, p []float64, y []int8, alpha []float64, penaltyCp, penaltyCn, eps float64, nu bool) Solver {
-
-	solver := Solver{l: l, q: q, p: p, y: y, alpha: alpha,
-		penaltyCp: penaltyCp, penaltyCn: penaltyCn, eps: eps}
+/**
+ * Returns a solver for the dual problem with linear term p, labels y, initial alpha and per variable upper bounds C
+ */
+func NewSolver(l int, q matrixQ, p []float64, y []int8, alpha []float64, C []float64, param *Parameter, nu bool) Solver {
+
+	// The solver works on its own copies since shrinking reorders the variables
+	solver := Solver{l: l, q: q, p: make([]float64, l), y: make([]int8, l), alpha: make([]float64, l),
+		C: make([]float64, l), eps: param.Eps, param: param, shrinking: param.Shrinking}
+	copy(solver.p, p)
+	copy(solver.y, y)
+	copy(solver.alpha, alpha)
+	copy(solver.C, C)
 	if nu {
 		solver.workingSet = selectWorkingSetNU{}
 	} else {
 		solver.workingSet = selectWorkingSet{}
 	}
 	solver.qd = q.getQD()
-	solver.parRunner = NewParallelRunner(solver.l)
+	solver.parRunner = newParallelRunnerLimit(solver.l, param.Parallelism)
 
 	return solver
 }
@@ -0,0 +1,136 @@
+package libSvm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newRandomProblem(l, n int, regression bool, seed int64) *Problem {
+	rng := rand.New(rand.NewSource(seed))
+	prob := &Problem{l: l}
+	for i := 0; i < l; i++ {
+		y := float64(1 - 2*rng.Intn(2))
+		prob.x = append(prob.x, len(prob.xSpace))
+		var sum float64 = 0
+		for j := 1; j <= n; j++ {
+			v := rng.NormFloat64() + 0.3*y
+			sum += v * float64(j%3-1)
+			prob.xSpace = append(prob.xSpace, snode{index: j, value: v})
+		}
+		prob.xSpace = append(prob.xSpace, snode{index: -1})
+		if regression {
+			y = sum + 0.1*rng.NormFloat64()
+		}
+		prob.y = append(prob.y, y)
+	}
+	return prob
+}
+
+func TestSolverShrinking(t *testing.T) {
+	for _, svmType := range []int{C_SVC, NU_SVC, ONE_CLASS, EPSILON_SVR, NU_SVR} {
+		prob := newRandomProblem(1000, 3, svmType == EPSILON_SVR || svmType == NU_SVR, 1)
+
+		var rho [2]float64
+		for k, shrinking := range []bool{false, true} {
+			param := NewParameter()
+			param.SvmType = svmType
+			param.C = 10
+			param.Nu = 0.7 // with a smaller nu the nu-SVC margin vanishes on this overlapping data
+			param.Gamma = 0.2
+			param.Shrinking = shrinking
+			param.QuietMode = true
+
+			model := NewModel(param)
+			if err := model.Train(prob); err != nil {
+				t.Fatal(err)
+			}
+			rho[k] = model.rho[0]
+		}
+
+		if math.Abs(rho[0]-rho[1]) > 1e-2 {
+			t.Errorf("%s: rho with shrinking %v, without %v", svm_type_string[svmType], rho[1], rho[0])
+		}
+	}
+}
+
+func TestSolverProgress(t *testing.T) {
+	prob := newRandomProblem(500, 3, false, 1)
+
+	param := NewParameter()
+	param.C = 100
+	param.Gamma = 10
+	param.QuietMode = true
+
+	var reports []SolverProgress
+	param.Progress = func(p SolverProgress) bool {
+		reports = append(reports, p)
+		return len(reports) < 2
+	}
+
+	model := NewModel(param)
+	err := model.Train(prob)
+
+	var stopped *TrainStoppedError
+	if !errors.As(err, &stopped) || stopped.Reason != STOPPED || !stopped.Partial {
+		t.Fatalf("Train error = %v, want a partial STOPPED error", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("progress was reported %d times, want 2", len(reports))
+	}
+	for k, p := range reports {
+		if p.Iter != 500*(k+1) {
+			t.Errorf("report %d: iter = %d, want %d", k, p.Iter, 500*(k+1))
+		}
+		if p.Gap <= param.Eps || p.NrFreeSV <= 0 || p.NrFreeSV > prob.l || p.Obj >= 0 {
+			t.Errorf("report %d: unexpected progress %+v", k, p)
+		}
+	}
+	if reports[1].Obj > reports[0].Obj {
+		t.Errorf("objective increased from %v to %v", reports[0].Obj, reports[1].Obj)
+	}
+}
+
+func TestSolverUnshrinkMarker(t *testing.T) {
+	prob := newRandomProblem(200, 3, false, 1)
+	var l int = prob.l
+
+	for _, nu := range []bool{false, true} {
+		var output string
+		param := NewParameter()
+		param.Gamma = 0.2
+		param.Logger = func(msg string) { output += msg }
+
+		y := make([]int8, l)
+		p := make([]float64, l)
+		alpha := make([]float64, l)
+		C := make([]float64, l)
+		sum := [2]float64{0.35 * float64(l), 0.35 * float64(l)} // nu = 0.7 for each class
+		for i := 0; i < l; i++ {
+			y[i] = int8(prob.y[i])
+			C[i] = 1
+			if !nu {
+				p[i] = -1
+				continue
+			}
+			k := (1 - int(y[i])) / 2
+			alpha[i] = minf(C[i], sum[k])
+			sum[k] -= alpha[i]
+		}
+
+		s := NewSolver(l, NewSVCQ(prob, param, y), p, y, alpha, C, param, nu)
+		if _, err := s.Solve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// at the optimum the shrinking heuristic reconstructs the gradient once and says so
+		output = ""
+		s.unshrink = false
+		s.workingSet.doShrinking(&s)
+		if !s.unshrink || output != "*" {
+			t.Errorf("nu = %v: unshrink = %v, output %q, want \"*\"", nu, s.unshrink, output)
+		}
+	}
+}
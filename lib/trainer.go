@@ -1,6 +1,7 @@
-package main
+package libSvm
 
 import (
+	"context"
 	"fmt"
 	"math"
 )
@@ -14,39 +15,97 @@ func (e *trainError) Error() string {
 	return fmt.Sprintf("%d -- %s\n", e.val, e.msg)
 }
 
+/**
+ * The reasons for which training can stop before the solver converged
+ */
+const (
+	MAX_ITER_REACHED = iota // Parameter.MaxIter (or the default limit) iterations were done
+	CANCELLED        = iota // the context of TrainContext was cancelled or timed out
+	STOPPED          = iota // Parameter.Progress asked the solver to stop
+)
+
+/**
+ * TrainStoppedError is returned by TrainContext when training stopped before the solver converged.
+ * If Partial is true the model holds the solution reached so far and can be used for prediction;
+ * otherwise the model was discarded and has to be trained again.
+ */
+type TrainStoppedError struct {
+	Reason  int   // MAX_ITER_REACHED, CANCELLED or STOPPED
+	Partial bool  // the model holds a partial (not converged) solution
+	Err     error // the context error if Reason is CANCELLED
+}
+
+func (e *TrainStoppedError) Error() string {
+	var msg string
+	switch e.Reason {
+	case CANCELLED:
+		msg = fmt.Sprintf("training cancelled: %v", e.Err)
+	case STOPPED:
+		msg = "training stopped by the progress callback"
+	default:
+		msg = "training stopped: maximal number of iterations reached"
+	}
+	if e.Partial {
+		return msg + "; the model holds a partial solution"
+	}
+	return msg + "; the model was discarded"
+}
+
+func (e *TrainStoppedError) Unwrap() error {
+	return e.Err
+}
+
+/**
+ * Returns true if err is nil or only reports a partial solution, i.e. the trained model is usable
+ */
+func isUsableModel(err error) bool {
+	if err == nil {
+		return true
+	}
+	stopped, ok := err.(*TrainStoppedError)
+	return ok && stopped.Partial
+}
+
 type solution struct {
-	obj           float64
-	rho           float64
-	upper_bound_p float64
-	upper_bound_n float64
-	alpha         []float64
-	r             float64
+	obj            float64
+	rho            float64
+	upper_bound    []float64 // upper bound C_i of each alpha_i
+	alpha          []float64
+	r              float64
+	maxIterReached bool // the solver stopped before it converged
+	stopped        bool // the solver was stopped by Parameter.Progress
 }
 
 type decision struct {
-	alpha []float64
-	rho   float64
+	alpha          []float64
+	rho            float64
+	maxIterReached bool
+	stopped        bool
 }
 
-func train_one(prob *Problem, param *Parameter, Cp, Cn float64) (decision, error) {
+func train_one(ctx context.Context, prob *Problem, param *Parameter, Cp, Cn float64) (decision, error) {
 
 	var si solution
+	var err error
 	switch param.SvmType {
 	case C_SVC:
-		si = solveCSVC(prob, param, Cp, Cn)
+		si, err = solveCSVC(ctx, prob, param, Cp, Cn)
 	case NU_SVC:
-		si = solveNuSVC(prob, param)
+		si, err = solveNuSVC(ctx, prob, param)
 	case ONE_CLASS:
-		si = solveOneClass(prob, param)
+		si, err = solveOneClass(ctx, prob, param)
 	case EPSILON_SVR:
-		si = solveEpsilonSVR(prob, param)
+		si, err = solveEpsilonSVR(ctx, prob, param)
 	case NU_SVR:
-		si = solveNuSVR(prob, param)
+		si, err = solveNuSVR(ctx, prob, param)
 	default:
 		return decision{}, &trainError{val: param.SvmType, msg: "svm type not supported"}
 	}
+	if err != nil {
+		return decision{}, err
+	}
 
-	fmt.Printf("obj = %f, rho = %f\n", si.obj, si.rho)
+	param.info("obj = %f, rho = %f\n", si.obj, si.rho)
 	alpha := si.alpha
 
 	var nSV int = 0
@@ -54,42 +113,42 @@ func train_one(prob *Problem, param *Parameter, Cp, Cn float64) (decision, error
 	for i := 0; i < prob.l; i++ {
 		if math.Abs(alpha[i]) > 0 {
 			nSV++
-			if prob.y[i] > 0 {
-				if math.Abs(alpha[i]) >= si.upper_bound_p {
-					nBSV++
-				}
-			} else {
-				if math.Abs(alpha[i]) >= si.upper_bound_n {
-					nBSV++
-				}
+			if math.Abs(alpha[i]) >= si.upper_bound[i] {
+				nBSV++
 			}
 		}
 	}
 
-	fmt.Printf("nSV = %d, nBSV = %d\n", nSV, nBSV)
+	param.info("nSV = %d, nBSV = %d\n", nSV, nBSV)
 
-	return decision{alpha: alpha, rho: si.rho}, nil
+	return decision{alpha: alpha, rho: si.rho, maxIterReached: si.maxIterReached, stopped: si.stopped}, nil
 }
 
-func solveCSVC(prob *Problem, param *Parameter, Cp, Cn float64) solution {
+func solveCSVC(ctx context.Context, prob *Problem, param *Parameter, Cp, Cn float64) (solution, error) {
 	var l int = prob.l
 
 	alpha := make([]float64, l)
 	minus_one := make([]float64, l)
 	y := make([]int8, l)
+	C := make([]float64, l)
 
 	for i := 0; i < l; i++ {
 		alpha[i] = 0
 		minus_one[i] = -1
 		if prob.y[i] > 0 {
 			y[i] = 1
+			C[i] = prob.weight(i) * Cp
 		} else {
 			y[i] = -1
+			C[i] = prob.weight(i) * Cn
 		}
 	}
 
-	s := NewSolver(l, NewSVCQ(prob, param, y), minus_one, y, alpha, Cp, Cn, param.Eps, false /*not nu*/)
-	si := s.Solve() // generate solution
+	s := NewSolver(l, NewSVCQ(prob, param, y), minus_one, y, alpha, C, param, false /*not nu*/)
+	si, err := s.Solve(ctx) // generate solution
+	if err != nil {
+		return si, err
+	}
 
 	var sum_alpha float64 = 0
 	for i := 0; i < l; i++ {
@@ -98,20 +157,21 @@ func solveCSVC(prob *Problem, param *Parameter, Cp, Cn float64) solution {
 	}
 
 	if Cp == Cn {
-		t := Cp * float64(l)
-		fmt.Printf("nu = %f\n", sum_alpha/t)
+		t := Cp * prob.sumWeights()
+		param.info("nu = %f\n", sum_alpha/t)
 	}
 
-	return si // return solution
+	return si, nil // return solution
 }
 
-func solveNuSVC(prob *Problem, param *Parameter) solution {
+func solveNuSVC(ctx context.Context, prob *Problem, param *Parameter) (solution, error) {
 	var l int = prob.l
 	var nu float64 = param.Nu
 
 	alpha := make([]float64, l)
 	y := make([]int8, l)
 	zeros := make([]float64, l)
+	C := make([]float64, l)
 
 	for i := 0; i < l; i++ {
 		if prob.y[i] > 0 {
@@ -119,17 +179,18 @@ func solveNuSVC(prob *Problem, param *Parameter) solution {
 		} else {
 			y[i] = -1
 		}
+		C[i] = prob.weight(i)
 	}
 
-	sum_pos := nu * float64(l) / 2
+	sum_pos := nu * prob.sumWeights() / 2
 	sum_neg := sum_pos
 
 	for i := 0; i < l; i++ {
 		if y[i] == 1 {
-			alpha[i] = minf(1, sum_pos)
+			alpha[i] = minf(C[i], sum_pos)
 			sum_pos -= alpha[i]
 		} else {
-			alpha[i] = minf(1, sum_neg)
+			alpha[i] = minf(C[i], sum_neg)
 			sum_neg -= alpha[i]
 		}
 	}
@@ -138,11 +199,14 @@ func solveNuSVC(prob *Problem, param *Parameter) solution {
 		zeros[i] = 0
 	}
 
-	s := NewSolver(l, NewSVCQ(prob, param, y), zeros, y, alpha, 1, 1, param.Eps, true /*nu*/)
-	si := s.Solve()
+	s := NewSolver(l, NewSVCQ(prob, param, y), zeros, y, alpha, C, param, true /*nu*/)
+	si, err := s.Solve(ctx)
+	if err != nil {
+		return si, err
+	}
 
 	r := si.r
-	fmt.Printf("C = %v\n", 1.0/r)
+	param.info("C = %v\n", 1.0/r)
 
 	for i := 0; i < l; i++ {
 		si.alpha[i] *= (float64(y[i]) / r)
@@ -150,28 +214,29 @@ func solveNuSVC(prob *Problem, param *Parameter) solution {
 
 	si.rho /= r
 	si.obj /= (r * r)
-	si.upper_bound_p = 1 / r
-	si.upper_bound_n = 1 / r
+	for i := 0; i < l; i++ {
+		si.upper_bound[i] /= r
+	}
 
-	return si
+	return si, nil
 }
 
-func solveOneClass(prob *Problem, param *Parameter) solution {
+func solveOneClass(ctx context.Context, prob *Problem, param *Parameter) (solution, error) {
 	var l int = prob.l
 
 	alpha := make([]float64, l)
 	zeros := make([]float64, l)
 	ones := make([]int8, l)
+	C := make([]float64, l)
 
-	var n int = int(param.Nu) * prob.l
-	for i := 0; i < n; i++ {
-		alpha[i] = 1
-	}
-	if n < l {
-		alpha[n] = param.Nu*float64(l) - float64(n)
+	for i := 0; i < l; i++ {
+		C[i] = prob.weight(i)
 	}
-	for i := n + 1; i < l; i++ {
-		alpha[i] = 0
+
+	nu_l := param.Nu * prob.sumWeights() // the first alphas are at the upper bound, the rest 0
+	for i := 0; i < l && nu_l > 0; i++ {
+		alpha[i] = minf(C[i], nu_l)
+		nu_l -= alpha[i]
 	}
 
 	for i := 0; i < l; i++ {
@@ -179,31 +244,40 @@ func solveOneClass(prob *Problem, param *Parameter) solution {
 		ones[i] = 1
 	}
 
-	s := NewSolver(l, NewOneClassQ(prob, param), zeros, ones, alpha, 1, 1, param.Eps, false /*not nu*/)
-	si := s.Solve()
+	s := NewSolver(l, NewOneClassQ(prob, param), zeros, ones, alpha, C, param, false /*not nu*/)
+	si, err := s.Solve(ctx)
+	if err != nil {
+		return si, err
+	}
 
-	return si
+	return si, nil
 }
 
-func solveEpsilonSVR(prob *Problem, param *Parameter) solution {
+func solveEpsilonSVR(ctx context.Context, prob *Problem, param *Parameter) (solution, error) {
 	var l int = prob.l
 
 	alpha := make([]float64, 2*l)
 	linear_term := make([]float64, 2*l)
 	y := make([]int8, 2*l)
+	C := make([]float64, 2*l)
 
 	for i := 0; i < l; i++ {
 		alpha[i] = 0
 		linear_term[i] = param.P - prob.y[i]
 		y[i] = 1
+		C[i] = prob.weight(i) * param.C
 
 		alpha[i+l] = 0
 		linear_term[i+l] = param.P + prob.y[i]
 		y[i+l] = -1
+		C[i+l] = C[i]
 	}
 
-	s := NewSolver(2*l, NewSVRQ(prob, param), linear_term, y, alpha, param.C, param.C, param.Eps, false /*not nu*/)
-	si := s.Solve()
+	s := NewSolver(2*l, NewSVRQ(prob, param), linear_term, y, alpha, C, param, false /*not nu*/)
+	si, err := s.Solve(ctx)
+	if err != nil {
+		return si, err
+	}
 
 	var sum_alpha float64 = 0
 	for i := 0; i < l; i++ {
@@ -212,24 +286,28 @@ func solveEpsilonSVR(prob *Problem, param *Parameter) solution {
 	}
 	si.alpha = si.alpha[:l]
 
-	var nu float64 = sum_alpha / (param.C * float64(l))
-	fmt.Printf("nu = %v\n", nu)
+	var nu float64 = sum_alpha / (param.C * prob.sumWeights())
+	param.info("nu = %v\n", nu)
 
-	return si
+	return si, nil
 }
 
-func solveNuSVR(prob *Problem, param *Parameter) solution {
+func solveNuSVR(ctx context.Context, prob *Problem, param *Parameter) (solution, error) {
 	var l int = prob.l
 	var C float64 = param.C
 
 	alpha := make([]float64, 2*l)
 	linear_term := make([]float64, 2*l)
 	y := make([]int8, 2*l)
+	upper := make([]float64, 2*l)
 
-	var sum float64 = C * param.Nu * float64(l) / 2.0
+	var sum float64 = C * param.Nu * prob.sumWeights() / 2.0
 
 	for i := 0; i < l; i++ {
-		alpha[i] = minf(sum, C)
+		upper[i] = prob.weight(i) * C
+		upper[i+l] = upper[i]
+
+		alpha[i] = minf(sum, upper[i])
 		alpha[i+l] = alpha[i]
 
 		sum -= alpha[i]
@@ -241,15 +319,18 @@ func solveNuSVR(prob *Problem, param *Parameter) solution {
 		y[i+l] = -1
 	}
 
-	s := NewSolver(2*l, NewSVRQ(prob, param), linear_term, y, alpha, param.C, param.C, param.Eps, true /*nu*/)
-	si := s.Solve()
+	s := NewSolver(2*l, NewSVRQ(prob, param), linear_term, y, alpha, upper, param, true /*nu*/)
+	si, err := s.Solve(ctx)
+	if err != nil {
+		return si, err
+	}
 
-	fmt.Printf("epsilon = %f\n", -si.r)
+	param.info("epsilon = %f\n", -si.r)
 
 	for i := 0; i < l; i++ {
 		si.alpha[i] = si.alpha[i] - si.alpha[i+l]
 	}
 	si.alpha = si.alpha[:l]
 
-	return si
+	return si, nil
 }
@@ -0,0 +1,47 @@
+package libSvm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestOneClassAlphaSum(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var y []float64
+	var X [][]float64
+	for i := 0; i < 100; i++ {
+		X = append(X, []float64{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()})
+		y = append(y, 1)
+	}
+	prob := denseTestProblem(y, X)
+
+	// with eps = 100 the solver stops at its initial point, which must already be feasible
+	for _, eps := range []float64{1e-3, 100} {
+		for _, nu := range []float64{0.5, 0.25} {
+			param := NewParameter()
+			param.SvmType = ONE_CLASS
+			param.Nu = nu
+			param.Gamma = 0.5
+			param.Eps = eps
+			param.QuietMode = true
+
+			model := NewModel(param)
+			if err := model.Train(prob); err != nil {
+				t.Fatal(err)
+			}
+
+			// the dual constraints of one-class SVM are 0 <= alpha_i <= 1 and sum(alpha) = nu * l
+			var sum float64 = 0
+			for i, coef := range model.svCoef[0] {
+				if coef < 0 || coef > 1 {
+					t.Fatalf("eps = %g, nu = %g: alpha of SV %d = %g, want it in [0,1]", eps, nu, i, coef)
+				}
+				sum += coef
+			}
+			if want := nu * float64(prob.l); math.Abs(sum-want) > 1e-6 {
+				t.Errorf("eps = %g, nu = %g: sum of alphas = %g, want %g", eps, nu, sum, want)
+			}
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package libSvm
 
 import (
 	"math"
@@ -7,6 +7,7 @@ import (
 type workingSetSelecter interface {
 	workingSetSelect(solver *Solver) (int, int, int)
 	calculateRho(solver *Solver) (float64, float64)
+	doShrinking(solver *Solver)
 }
 
 type selectWorkingSet struct{}
@@ -20,7 +21,7 @@ func (s selectWorkingSet) workingSetSelect(solver *Solver) (int, int, int) {
 	var gmax_idx int = -1
 	var gmin_idx int = -1
 
-	for i := 0; i < solver.l; i++ {
+	for i := 0; i < solver.activeSize; i++ {
 		if solver.y[i] == 1 {
 			if !solver.isUpperBound(i) {
 				if -solver.gradient[i] >= gmax {
@@ -48,15 +49,16 @@ func (s selectWorkingSet) workingSetSelect(solver *Solver) (int, int, int) {
 		}
 	}
 
+	solver.gap = gmax + gmax2 // maximal violating pair
 	if gmax+gmax2 < solver.eps {
 		return -1, -1, 1
 	}
 
 	i := gmax_idx
 
-	Qi := solver.q.getQ(i, solver.l)
+	Qi := solver.q.getQ(i, solver.activeSize)
 
-	for j := 0; j < solver.l; j++ {
+	for j := 0; j < solver.activeSize; j++ {
 		if solver.y[j] == 1 {
 			if !solver.isLowerBound(j) {
 				grad_diff := gmax + solver.gradient[j]
@@ -83,7 +85,7 @@ func (s selectWorkingSet) workingSetSelect(solver *Solver) (int, int, int) {
 					if quad_coeff > 0 {
 						obj_diff = -(grad_diff * grad_diff) / quad_coeff
 					} else {
-						obj_diff = -(grad_diff * grad_diff) / quad_coeff
+						obj_diff = -(grad_diff * grad_diff) / TAU
 					}
 					if obj_diff <= obj_diff_min {
 						obj_diff_min = obj_diff
@@ -94,6 +96,10 @@ func (s selectWorkingSet) workingSetSelect(solver *Solver) (int, int, int) {
 		}
 	}
 
+	if gmin_idx == -1 {
+		return -1, -1, 1
+	}
+
 	//fmt.Printf("gmax_idx=%d, gmin_idx=%d\n", gmax_idx, gmin_idx)
 	return gmax_idx, gmin_idx, 0
 }
@@ -104,7 +110,7 @@ func (s selectWorkingSet) calculateRho(solver *Solver) (float64, float64) {
 	var sum_free float64 = 0
 	var nr_free int = 0
 	var r float64 = 0
-	for i := 0; i < solver.l; i++ {
+	for i := 0; i < solver.activeSize; i++ {
 		yG := float64(solver.y[i]) * solver.gradient[i]
 		if solver.isUpperBound(i) {
 			if solver.y[i] == -1 {
@@ -145,7 +151,7 @@ func (s selectWorkingSetNU) workingSetSelect(solver *Solver) (int, int, int) {
 	var obj_diff_min float64 = math.MaxFloat64
 	var gmin_idx int = -1
 
-	for i := 0; i < solver.l; i++ {
+	for i := 0; i < solver.activeSize; i++ {
 		if solver.y[i] == 1 {
 			if !solver.isUpperBound(i) {
 				if -solver.gradient[i] >= gmaxp {
@@ -173,6 +179,7 @@ func (s selectWorkingSetNU) workingSetSelect(solver *Solver) (int, int, int) {
 		}
 	}
 
+	solver.gap = maxf(gmaxp+gmaxp2, gmaxn+gmaxn2) // maximal violating pair
 	if maxf(gmaxp+gmaxp2, gmaxn+gmaxn2) < solver.eps {
 		return -1, -1, 1 // done!
 	}
@@ -182,14 +189,14 @@ func (s selectWorkingSetNU) workingSetSelect(solver *Solver) (int, int, int) {
 
 	var Qip []float64
 	if ip != -1 {
-		Qip = solver.q.getQ(ip, solver.l)
+		Qip = solver.q.getQ(ip, solver.activeSize)
 	}
 	var Qin []float64
 	if in != -1 {
-		Qin = solver.q.getQ(in, solver.l)
+		Qin = solver.q.getQ(in, solver.activeSize)
 	}
 
-	for j := 0; j < solver.l; j++ {
+	for j := 0; j < solver.activeSize; j++ {
 		if solver.y[j] == 1 {
 			if !solver.isLowerBound(j) {
 				grad_diff := gmaxp + solver.gradient[j]
@@ -229,6 +236,10 @@ func (s selectWorkingSetNU) workingSetSelect(solver *Solver) (int, int, int) {
 		}
 	}
 
+	if gmin_idx == -1 {
+		return -1, -1, 1
+	}
+
 	var out_j int = gmin_idx
 	var out_i int
 	if solver.y[out_j] == 1 {
@@ -250,7 +261,7 @@ func (s selectWorkingSetNU) calculateRho(solver *Solver) (float64, float64) {
 	var sum_free1 float64 = 0
 	var sum_free2 float64 = 0
 
-	for i := 0; i < solver.l; i++ {
+	for i := 0; i < solver.activeSize; i++ {
 		if solver.y[i] == 1 {
 			if solver.isUpperBound(i) {
 				lb1 = maxf(lb1, solver.gradient[i])
@@ -290,3 +301,104 @@ func (s selectWorkingSetNU) calculateRho(solver *Solver) (float64, float64) {
 
 	return (r1 - r2) / 2, (r1 + r2) / 2
 }
+
+func (s selectWorkingSet) beShrunk(solver *Solver, i int, gmax1, gmax2 float64) bool {
+	if solver.isUpperBound(i) {
+		if solver.y[i] == 1 {
+			return -solver.gradient[i] > gmax1
+		} else {
+			return -solver.gradient[i] > gmax2
+		}
+	} else if solver.isLowerBound(i) {
+		if solver.y[i] == 1 {
+			return solver.gradient[i] > gmax2
+		} else {
+			return solver.gradient[i] > gmax1
+		}
+	}
+	return false
+}
+
+func (s selectWorkingSet) doShrinking(solver *Solver) {
+	var gmax1 float64 = -math.MaxFloat64 // max { -y_i * grad(f)_i | i in I_up(\alpha) }
+	var gmax2 float64 = -math.MaxFloat64 // max { y_i * grad(f)_i | i in I_low(\alpha) }
+
+	// find maximal violating pair first
+	for i := 0; i < solver.activeSize; i++ {
+		if solver.y[i] == 1 {
+			if !solver.isUpperBound(i) {
+				gmax1 = maxf(gmax1, -solver.gradient[i])
+			}
+			if !solver.isLowerBound(i) {
+				gmax2 = maxf(gmax2, solver.gradient[i])
+			}
+		} else {
+			if !solver.isUpperBound(i) {
+				gmax2 = maxf(gmax2, -solver.gradient[i])
+			}
+			if !solver.isLowerBound(i) {
+				gmax1 = maxf(gmax1, solver.gradient[i])
+			}
+		}
+	}
+
+	if !solver.unshrink && gmax1+gmax2 <= solver.eps*10 {
+		solver.unshrink = true
+		solver.reconstructGradient()
+		solver.activeSize = solver.l
+		solver.param.info("*")
+	}
+
+	solver.shrink(func(i int) bool { return s.beShrunk(solver, i, gmax1, gmax2) })
+}
+
+func (s selectWorkingSetNU) beShrunk(solver *Solver, i int, gmax1, gmax2, gmax3, gmax4 float64) bool {
+	if solver.isUpperBound(i) {
+		if solver.y[i] == 1 {
+			return -solver.gradient[i] > gmax1
+		} else {
+			return -solver.gradient[i] > gmax4
+		}
+	} else if solver.isLowerBound(i) {
+		if solver.y[i] == 1 {
+			return solver.gradient[i] > gmax2
+		} else {
+			return solver.gradient[i] > gmax3
+		}
+	}
+	return false
+}
+
+func (s selectWorkingSetNU) doShrinking(solver *Solver) {
+	var gmax1 float64 = -math.MaxFloat64 // max { -y_i * grad(f)_i | y_i = +1, i in I_up(\alpha) }
+	var gmax2 float64 = -math.MaxFloat64 // max { y_i * grad(f)_i | y_i = +1, i in I_low(\alpha) }
+	var gmax3 float64 = -math.MaxFloat64 // max { -y_i * grad(f)_i | y_i = -1, i in I_up(\alpha) }
+	var gmax4 float64 = -math.MaxFloat64 // max { y_i * grad(f)_i | y_i = -1, i in I_low(\alpha) }
+
+	// find maximal violating pair first
+	for i := 0; i < solver.activeSize; i++ {
+		if !solver.isUpperBound(i) {
+			if solver.y[i] == 1 {
+				gmax1 = maxf(gmax1, -solver.gradient[i])
+			} else {
+				gmax4 = maxf(gmax4, -solver.gradient[i])
+			}
+		}
+		if !solver.isLowerBound(i) {
+			if solver.y[i] == 1 {
+				gmax2 = maxf(gmax2, solver.gradient[i])
+			} else {
+				gmax3 = maxf(gmax3, solver.gradient[i])
+			}
+		}
+	}
+
+	if !solver.unshrink && maxf(gmax1+gmax2, gmax3+gmax4) <= solver.eps*10 {
+		solver.unshrink = true
+		solver.reconstructGradient()
+		solver.activeSize = solver.l
+		solver.param.info("*")
+	}
+
+	solver.shrink(func(i int) bool { return s.beShrunk(solver, i, gmax1, gmax2, gmax3, gmax4) })
+}
@@ -1,8 +1,11 @@
-package main
+package libSvm
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"math/rand"
+	"strings"
 )
 
 /**
@@ -12,15 +15,107 @@ import (
    labels (of all prob's instances) in the validation process are
    stored in the array called target.
 
+   If the training of a fold fails, the error is written with the
+   training output (see Parameter.QuietMode) and nil is returned;
+   use CrossValidationContext to get the error instead.
 */
 func CrossValidation(prob *Problem, param *Parameter, nrFold int) (target []float64) {
+	target, err := CrossValidationContext(context.Background(), prob, param, nrFold)
+	if err != nil {
+		param.info("ERROR: cross validation failed: %s\n", strings.TrimSpace(err.Error()))
+	}
+	return
+}
+
+/**
+ * Same as CrossValidation, but stops when ctx is cancelled and returns the error of the training that was
+ * interrupted. A fold whose solver hits Parameter.MaxIter still predicts with its partial model.
+ */
+func CrossValidationContext(ctx context.Context, prob *Problem, param *Parameter, nrFold int) (target []float64, err error) {
+	return CrossValidationRand(ctx, prob, param, nrFold, param.newRand())
+}
+
+/**
+ * Same as CrossValidationContext, but draws the folds, and the folds of the probability estimates of
+ * each model, from rng instead of a source seeded with Parameter.Seed. The same rng state gives the
+ * same target.
+ */
+func CrossValidationRand(ctx context.Context, prob *Problem, param *Parameter, nrFold int, rng *rand.Rand) (target []float64, err error) {
+	return crossValidation(ctx, prob, param, nrFold, rng, func(subModel *Model, x map[int]float64) float64 {
+		if param.Probability &&
+			(param.SvmType == C_SVC || param.SvmType == NU_SVC) {
+			predictLabel, _ := subModel.PredictProbability(x)
+			return predictLabel
+		}
+		return subModel.Predict(x)
+	})
+}
+
+/**
+ * Same as CrossValidation for a two-class C_SVC or NU_SVC problem, but collects the out-of-fold
+ * decision values instead of the predicted labels, e.g. to compute a ROC curve. With
+ * Parameter.Probability the scores are the probability estimates of the positive class instead.
+ *
+ * The positive class is the first label of the problem, as for Model.PredictValues (+1 for -1/+1
+ * labels). A larger score means the instance is more likely to be of the positive class.
+ */
+func CrossValidationDecisionValues(prob *Problem, param *Parameter, nrFold int) (scores []float64, positive float64, err error) {
+	return CrossValidationDecisionValuesContext(context.Background(), prob, param, nrFold)
+}
+
+/**
+ * Same as CrossValidationDecisionValues, but stops when ctx is cancelled.
+ */
+func CrossValidationDecisionValuesContext(ctx context.Context, prob *Problem, param *Parameter, nrFold int) (scores []float64, positive float64, err error) {
+	if param.SvmType != C_SVC && param.SvmType != NU_SVC {
+		return nil, 0, fmt.Errorf("Fail to collect decision values: only C_SVC and NU_SVC are supported\n")
+	}
+	nrClass, label, _, _, _ := groupClasses(prob)
+	if nrClass != 2 {
+		return nil, 0, fmt.Errorf("Fail to collect decision values: the problem has %d classes, need 2\n", nrClass)
+	}
+	positive = label[0]
+
+	scores, err = crossValidation(ctx, prob, param, nrFold, param.newRand(), func(subModel *Model, x map[int]float64) float64 {
+		if subModel.nrClass == 1 { // the training folds held a single class
+			if subModel.label[0] == positive {
+				return math.Inf(1)
+			}
+			return math.Inf(-1)
+		}
+
+		var score float64
+		if param.Probability {
+			_, probEstimates := subModel.PredictProbability(x)
+			score = probEstimates[0]
+			if subModel.label[0] != positive {
+				score = probEstimates[1]
+			}
+		} else {
+			_, decisionValues := subModel.PredictValues(x)
+			score = decisionValues[0]
+			if subModel.label[0] != positive { // the fold saw the other class first
+				score = -score
+			}
+		}
+		return score
+	})
+	return scores, positive, err
+}
+
+/**
+ * Trains a model on all but one fold in turn and stores predict(subModel, x) of every instance x of the
+ * remaining fold in target
+ */
+func crossValidation(ctx context.Context, prob *Problem, param *Parameter, nrFold int, rng *rand.Rand,
+	predict func(subModel *Model, x map[int]float64) float64) (target []float64, err error) {
 	var l int = prob.l
 
 	target = make([]float64, l) // slice to return
 
 	if nrFold > l {
 		nrFold = l
-		fmt.Printf("WARNING: # folds > # data. Will use # folds = # data instead (i.e., leave-one-out cross validation)\n")
+		param.info("WARNING: # folds > # data. Will use # folds = # data instead (i.e., leave-one-out cross validation)\n")
 	}
 
 	foldStart := make([]int, nrFold+1)
@@ -31,18 +126,18 @@ func CrossValidation(prob *Problem, param *Parameter, nrFold int) (target []floa
 	// Each class to l folds -> some folds may have zero elements
 	if (param.SvmType == C_SVC || param.SvmType == NU_SVC) && nrFold < l {
 
-		nrClass, _, start, count, perm := groupClasses(prob) // group SV with the same labels together
+		nrClass, _, start, count, classPerm := groupClasses(prob) // group SV with the same labels together
 
 		// random shuffle and then data grouped by fold using the array perm
 		foldCount := make([]int, nrFold)
 		index := make([]int, l)
 		for i := 0; i < l; i++ {
-			index[i] = perm[i]
+			index[i] = classPerm[i]
 		}
 
 		for c := 0; c < nrClass; c++ {
 			for i := 0; i < count[c]; i++ {
-				j := i + rand.Intn(count[c]-i)
+				j := i + rng.Intn(count[c]-i)
 				index[start[c]+j], index[start[c]+i] = index[start[c]+i], index[start[c]+j]
 			}
 		}
@@ -81,7 +176,7 @@ func CrossValidation(prob *Problem, param *Parameter, nrFold int) (target []floa
 		}
 
 		for i := 0; i < l; i++ {
-			j := i + rand.Intn(l-i)
+			j := i + rng.Intn(l-i)
 			perm[i], perm[j] = perm[j], perm[i]
 		}
 
@@ -96,38 +191,43 @@ func CrossValidation(prob *Problem, param *Parameter, nrFold int) (target []floa
 
 		var subProb Problem
 
+		subProb.xSpace = prob.xSpace // inherits the space
 		subProb.l = l - (end - begin)
 		subProb.x = make([]int, subProb.l)
 		subProb.y = make([]float64, subProb.l)
 
+		if prob.w != nil {
+			subProb.w = make([]float64, subProb.l)
+		}
+
 		var k int = 0
 		for j := 0; j < begin; j++ {
 			subProb.x[k] = prob.x[perm[j]]
 			subProb.y[k] = prob.y[perm[j]]
+			if prob.w != nil {
+				subProb.w[k] = prob.w[perm[j]]
+			}
 			k++
 		}
 		for j := end; j < l; j++ {
 			subProb.x[k] = prob.x[perm[j]]
 			subProb.y[k] = prob.y[perm[j]]
+			if prob.w != nil {
+				subProb.w[k] = prob.w[perm[j]]
+			}
 			k++
 		}
 
 		subModel := NewModel(param)
-		subModel.Train(&subProb)
+		if err = subModel.train(ctx, &subProb, rng); !isUsableModel(err) {
+			return nil, err
+		}
+		err = nil
 
-		if param.Probability &&
-			(param.SvmType == C_SVC || param.SvmType == NU_SVC) {
-			for j := begin; j < end; j++ {
-				idx := prob.x[perm[j]]
-				x := SnodeToMap(prob.xSpace[idx:])
-				target[perm[j]], _ = subModel.PredictProbability(x)
-			}
-		} else {
-			for j := begin; j < end; j++ {
-				idx := prob.x[perm[j]]
-				x := SnodeToMap(prob.xSpace[idx:])
-				target[perm[j]] = subModel.Predict(x)
-			}
+		for j := begin; j < end; j++ {
+			idx := prob.x[perm[j]]
+			x := SnodeToMap(prob.xSpace[idx:])
+			target[perm[j]] = predict(subModel, x)
 		}
 	}
 
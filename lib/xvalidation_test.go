@@ -0,0 +1,40 @@
+package libSvm
+
+import (
+	"testing"
+)
+
+func TestCrossValidationSeparable(t *testing.T) {
+	var y []float64
+	var X [][]float64
+	for i := 0; i < 40; i++ {
+		label := float64(1 - 2*(i%2))
+		y = append(y, label)
+		X = append(X, []float64{label * (1 + float64(i)/40)})
+	}
+	prob := denseTestProblem(y, X)
+
+	param := NewParameter()
+	param.Gamma = 1
+	param.Probability = true
+	param.QuietMode = true
+
+	// the training folds must be drawn from the whole problem and share its feature space
+	target := CrossValidation(prob, param, 4)
+	for i := range target {
+		if target[i] != y[i] {
+			t.Fatalf("target[%d] = %g, want %g", i, target[i], y[i])
+		}
+	}
+
+	model := NewModel(param)
+	if err := model.Train(prob); err != nil {
+		t.Fatal(err)
+	}
+	for i, x := range X {
+		label, estimates := model.PredictProbability(map[int]float64{1: x[0]})
+		if label != y[i] || maxf(estimates[0], estimates[1]) < 0.5 {
+			t.Fatalf("instance %d: label %g with estimates %v, want %g", i, label, estimates, y[i])
+		}
+	}
+}